@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/registrobr/rdap-client/output"
+)
+
+// readIdentifiers loads the objects to query from a file, one per line. The
+// name “-” reads from the standard input. Blank lines and lines starting
+// with “#” are ignored
+func readIdentifiers(name string) ([]string, error) {
+	var r io.Reader = os.Stdin
+
+	if name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		r = f
+	}
+
+	var identifiers []string
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		identifiers = append(identifiers, line)
+	}
+
+	return identifiers, scanner.Err()
+}
+
+// lookupAll resolves the identifiers using at most workers concurrent
+// lookups. The results are handed to the callback in the same order of the
+// identifiers, as soon as each one and all its predecessors are done
+func lookupAll(identifiers []string, workers int, lookup func(string) *output.Result, callback func(*output.Result)) {
+	if workers < 1 {
+		workers = 1
+	}
+
+	results := make([]chan *output.Result, len(identifiers))
+	for i := range results {
+		results[i] = make(chan *output.Result, 1)
+	}
+
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for j := range jobs {
+				results[j] <- lookup(identifiers[j])
+			}
+		}()
+	}
+
+	go func() {
+		for i := range identifiers {
+			jobs <- i
+		}
+		close(jobs)
+	}()
+
+	for _, r := range results {
+		callback(<-r)
+	}
+
+	wg.Wait()
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/registrobr/rdap-client/output"
+)
+
+func TestReadIdentifiers(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "objects")
+	content := "nic.br\n\n# comment\n  200.160.0.0/20  \n1234\n"
+
+	if err := os.WriteFile(name, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	identifiers, err := readIdentifiers(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"nic.br", "200.160.0.0/20", "1234"}
+	if !reflect.DeepEqual(identifiers, expected) {
+		t.Fatalf("expected %v and got %v", expected, identifiers)
+	}
+}
+
+func TestLookupAllKeepsOrder(t *testing.T) {
+	identifiers := []string{"a", "b", "c", "d", "e"}
+	delays := map[string]time.Duration{
+		"a": 40 * time.Millisecond,
+		"b": 10 * time.Millisecond,
+		"c": 30 * time.Millisecond,
+		"d": 0,
+		"e": 20 * time.Millisecond,
+	}
+
+	lookup := func(identifier string) *output.Result {
+		time.Sleep(delays[identifier])
+		if identifier == "c" {
+			return &output.Result{Query: identifier, Err: errors.New("failed")}
+		}
+
+		return &output.Result{Query: identifier, Object: identifier}
+	}
+
+	var results []*output.Result
+	lookupAll(identifiers, 3, lookup, func(r *output.Result) {
+		results = append(results, r)
+	})
+
+	if len(results) != len(identifiers) {
+		t.Fatalf("expected %d results and got %d", len(identifiers), len(results))
+	}
+
+	for i, r := range results {
+		if r.Query != identifiers[i] {
+			t.Errorf("result %d: expected “%s” and got “%s”", i, identifiers[i], r.Query)
+		}
+
+		if r.Query == "c" {
+			if r.Err == nil {
+				t.Error("expected an error for “c”")
+			}
+		} else if r.Err != nil || r.Object != r.Query {
+			t.Errorf("unexpected result for “%s”: %v, %v", r.Query, r.Object, r.Err)
+		}
+	}
+}
@@ -0,0 +1,534 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/registrobr/rdap"
+	"github.com/registrobr/rdap-client/output"
+)
+
+// serviceRegistry reflects the structure of an RDAP bootstrap service
+// registry, as described in RFC 9224, section 3
+type serviceRegistry struct {
+	Version     string        `json:"version"`
+	Publication string        `json:"publication"`
+	Description string        `json:"description,omitempty"`
+	Services    [][2][]string `json:"services"`
+}
+
+func fetchServiceRegistry(httpClient httpDoer, uri string) (*serviceRegistry, error) {
+	var registry serviceRegistry
+	if err := fetchBootstrapRegistry(httpClient, uri, &registry); err != nil {
+		return nil, err
+	}
+
+	return &registry, nil
+}
+
+// objectTagRegistry reflects the structure of the bootstrap service registry
+// for provider object tags, where each service also lists the contacts of
+// the registry, as described in RFC 8521, section 2
+type objectTagRegistry struct {
+	Version     string        `json:"version"`
+	Publication string        `json:"publication"`
+	Description string        `json:"description,omitempty"`
+	Services    [][3][]string `json:"services"`
+}
+
+func fetchObjectTagRegistry(httpClient httpDoer, uri string) (*objectTagRegistry, error) {
+	var registry objectTagRegistry
+	if err := fetchBootstrapRegistry(httpClient, uri, &registry); err != nil {
+		return nil, err
+	}
+
+	return &registry, nil
+}
+
+func fetchBootstrapRegistry(httpClient httpDoer, uri string, registry any) error {
+	req, err := http.NewRequest(http.MethodGet, uri, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Add("Accept", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotModified {
+		return fmt.Errorf("unexpected status code %d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+
+	return json.NewDecoder(resp.Body).Decode(registry)
+}
+
+// matchDomain returns the URIs of the service with the label-wise longest
+// match of the domain name, as described in RFC 9224, section 4, and the
+// matched entry
+func (s *serviceRegistry) matchDomain(fqdn string) (uris []string, match string) {
+	var longest int
+
+	fqdnParts := strings.Split(strings.TrimSuffix(strings.ToLower(fqdn), "."), ".")
+
+	for _, service := range s.Services {
+	entries:
+		for _, entry := range service[0] {
+			entryParts := strings.Split(strings.ToLower(entry), ".")
+			if len(entryParts) > len(fqdnParts) || len(entryParts) <= longest {
+				continue
+			}
+
+			fqdnExcerpt := fqdnParts[len(fqdnParts)-len(entryParts):]
+			for i := range entryParts {
+				if fqdnExcerpt[i] != entryParts[i] {
+					continue entries
+				}
+			}
+
+			uris = service[1]
+			match = entry
+			longest = len(entryParts)
+		}
+	}
+
+	return prioritizeHTTPS(uris), match
+}
+
+// matchIP returns the entry of an IP registry with the longest prefix that
+// covers the address or the network, as described in RFC 9224, section 5.1.
+// Like in the library, the first entry wins the ties and the prefixes of
+// length zero never match
+func (s *serviceRegistry) matchIP(value string) string {
+	ip, bits := net.ParseIP(value), -1
+	if ip == nil {
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			return ""
+		}
+
+		ip = network.IP
+		bits, _ = network.Mask.Size()
+	}
+
+	var (
+		match   string
+		longest = 0
+	)
+
+	for _, service := range s.Services {
+		for _, entry := range service[0] {
+			_, network, err := net.ParseCIDR(entry)
+			if err != nil {
+				continue
+			}
+
+			size, _ := network.Mask.Size()
+			if network.Contains(ip) && size > longest && (bits < 0 || size <= bits) {
+				match, longest = entry, size
+			}
+		}
+	}
+
+	return match
+}
+
+// matchASN returns the entry of the ASN registry that covers the AS number,
+// as described in RFC 9224, section 5.3. Like in the library, an entry with
+// the exact number wins, then the smallest range, with the first entry
+// winning the ties
+func (s *serviceRegistry) matchASN(value string) string {
+	asn, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return ""
+	}
+
+	var (
+		match    string
+		smallest = uint64(math.MaxUint32)
+	)
+
+	for _, service := range s.Services {
+		for _, entry := range service[0] {
+			first, last, found := strings.Cut(entry, "-")
+			if !found {
+				if number, err := strconv.ParseUint(entry, 10, 32); err == nil && number == asn {
+					return entry
+				}
+
+				continue
+			}
+
+			start, errStart := strconv.ParseUint(first, 10, 32)
+			end, errEnd := strconv.ParseUint(last, 10, 32)
+			if errStart == nil && errEnd == nil && start <= asn && asn <= end && end-start < smallest {
+				match, smallest = entry, end-start
+			}
+		}
+	}
+
+	return match
+}
+
+// matchHandle returns the URIs of the service with the tag of the entity
+// handle, that is the text after its last hyphen, as described in RFC 8521,
+// section 3, and the matched tag
+func (o *objectTagRegistry) matchHandle(handle string) (uris []string, tag string) {
+	i := strings.LastIndex(handle, "-")
+	if i < 0 {
+		return nil, ""
+	}
+
+	for _, service := range o.Services {
+		for _, entry := range service[1] {
+			if strings.EqualFold(entry, handle[i+1:]) {
+				return prioritizeHTTPS(service[2]), entry
+			}
+		}
+	}
+
+	return nil, ""
+}
+
+// prioritizeHTTPS returns a copy of the URIs with the HTTPS ones first
+func prioritizeHTTPS(uris []string) []string {
+	var secure, insecure []string
+
+	for _, uri := range uris {
+		if strings.HasPrefix(uri, "https://") {
+			secure = append(secure, uri)
+		} else {
+			insecure = append(insecure, uri)
+		}
+	}
+
+	return append(secure, insecure...)
+}
+
+// registryFetcher is a Fetcher that finds the RDAP servers of the query in
+// the bootstrap service registries, using rdap.NewBootstrapFetcher for the
+// object types it covers, and queries them in order. When result is set, the
+// matched entry and the servers that failed are kept in it, and the matched
+// entry is also traced
+type registryFetcher struct {
+	fetcher       *failoverFetcher
+	httpClient    *http.Client
+	bootstrapURI  string
+	cacheDetector rdap.CacheDetector
+	tracer        *tracer
+	result        *output.Result
+}
+
+func (r *registryFetcher) withResult(result *output.Result) rdap.Fetcher {
+	fetcher := *r
+	fetcher.fetcher = r.fetcher.withResult(result).(*failoverFetcher)
+	fetcher.result = result
+	return &fetcher
+}
+
+func (r *registryFetcher) Fetch(uris []string, queryType rdap.QueryType, queryValue string, header http.Header, queryString url.Values) (*http.Response, error) {
+	if len(uris) > 0 {
+		return r.fetcher.Fetch(uris, queryType, queryValue, header, queryString)
+	}
+
+	var (
+		match    *output.BootstrapMatch
+		err      error
+		resolver = &bootstrapResolver{httpClient: r.httpClient, cacheDetector: r.cacheDetector}
+	)
+
+	switch queryType {
+	case queryTypeNameserver:
+		// nameservers are found in the same registry of the domains
+		uris, match, err = r.matchNameserver(resolver, queryValue)
+	case rdap.QueryTypeEntity:
+		uris, match, err = r.matchEntity(resolver, queryValue)
+	default:
+		fetcher := rdap.NewBootstrapFetcher(resolver, r.bootstrapURI, resolver.detected)
+
+		_, err := fetcher.Fetch(nil, queryType, queryValue, header, queryString)
+		if len(resolver.urls) == 0 {
+			return nil, err
+		}
+
+		if r.tracer != nil || r.result != nil {
+			if match = libraryMatch(resolver, queryValue); match != nil {
+				r.traceMatch(resolver, match.Entry, resolver.urls)
+				r.recordMatch(match)
+			}
+		}
+
+		return r.fetcher.fetchURLs(resolver.urls, header)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	r.traceMatch(resolver, match.Entry, uris)
+	r.recordMatch(match)
+
+	return r.fetcher.Fetch(uris, queryType, queryValue, header, queryString)
+}
+
+func (r *registryFetcher) matchNameserver(resolver *bootstrapResolver, name string) ([]string, *output.BootstrapMatch, error) {
+	registry, err := fetchServiceRegistry(resolver, fmt.Sprintf(r.bootstrapURI, "dns"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	uris, entry := registry.matchDomain(name)
+	if len(uris) == 0 {
+		return nil, nil, &rdap.ErrNoMatch{QueryValue: name}
+	}
+
+	return uris, &output.BootstrapMatch{Registry: "dns", Entry: entry}, nil
+}
+
+func (r *registryFetcher) matchEntity(resolver *bootstrapResolver, handle string) ([]string, *output.BootstrapMatch, error) {
+	registry, err := fetchObjectTagRegistry(resolver, fmt.Sprintf(r.bootstrapURI, "object-tags"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	uris, tag := registry.matchHandle(handle)
+	if len(uris) == 0 {
+		return nil, nil, &rdap.ErrNoMatch{QueryValue: handle}
+	}
+
+	return uris, &output.BootstrapMatch{Registry: "object-tags", Entry: tag}, nil
+}
+
+// traceMatch writes to the trace the entry matched in the last registry
+// retrieved by the resolver, and the URLs of the servers
+func (r *registryFetcher) traceMatch(resolver *bootstrapResolver, entry string, uris []string) {
+	r.tracer.printf("bootstrap entry “%s” matched in %s: %s", entry, resolver.registryURL, strings.Join(uris, ", "))
+}
+
+// recordMatch keeps the matched entry in the result, when there's one
+func (r *registryFetcher) recordMatch(match *output.BootstrapMatch) {
+	if r.result != nil {
+		r.result.Bootstrap = match
+	}
+}
+
+// libraryMatch finds again the entry of the registry matched by the library,
+// that doesn't tell it, by the kind of the registry. It returns nil when the
+// entry isn't found
+func libraryMatch(resolver *bootstrapResolver, queryValue string) *output.BootstrapMatch {
+	var registry serviceRegistry
+	if json.Unmarshal(resolver.registry, &registry) != nil {
+		return nil
+	}
+
+	u, err := url.Parse(resolver.registryURL)
+	if err != nil {
+		return nil
+	}
+
+	match := &output.BootstrapMatch{Registry: strings.TrimSuffix(path.Base(u.Path), ".json")}
+
+	switch match.Registry {
+	case "dns":
+		_, match.Entry = registry.matchDomain(queryValue)
+	case "ipv4", "ipv6":
+		match.Entry = registry.matchIP(queryValue)
+	case "asn":
+		match.Entry = registry.matchASN(queryValue)
+	}
+
+	if match.Entry == "" {
+		return nil
+	}
+
+	return match
+}
+
+// bootstrapKinds are the bootstrap service registries, named after their
+// files in the bootstrap service
+var bootstrapKinds = []string{"dns", "ipv4", "ipv6", "asn", "object-tags"}
+
+// parseBootstrapFiles reads the local bootstrap files, given as “kind=path”
+// or as the path of a file named after the registry, like “dns.json”
+func parseBootstrapFiles(files []string) (map[string]string, error) {
+	kinds := make(map[string]string)
+
+	for _, file := range files {
+		kind, path, ok := strings.Cut(file, "=")
+		if !ok {
+			path = file
+			kind = strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+		}
+
+		if !slices.Contains(bootstrapKinds, kind) {
+			return nil, fmt.Errorf("invalid bootstrap file “%s”, unknown registry “%s” (expected “%s”)",
+				file, kind, strings.Join(bootstrapKinds, "”, “"))
+		}
+
+		kinds[kind] = path
+	}
+
+	return kinds, nil
+}
+
+var asnEntryRX = regexp.MustCompile(`^[0-9]+(-[0-9]+)?$`)
+
+// bootstrapOverrideKind returns the registry of a bootstrap entry: IP
+// networks, AS numbers and ranges, or else domain names
+func bootstrapOverrideKind(entry string) string {
+	if ip, _, err := net.ParseCIDR(entry); err == nil {
+		if ip.To4() != nil {
+			return "ipv4"
+		}
+
+		return "ipv6"
+	}
+
+	if asnEntryRX.MatchString(entry) {
+		return "asn"
+	}
+
+	return "dns"
+}
+
+// parseBootstrapOverrides reads the services that take precedence over the
+// equally specific entries of the bootstrap registries, given as “entry=URL”
+// (e.g. “.br=https://rdap.br/”). An IP address is the network of the address
+// alone, and the URLs of a repeated entry are kept in order
+func parseBootstrapOverrides(overrides []string) (map[string][][2][]string, error) {
+	services := make(map[string][][2][]string)
+
+	for _, override := range overrides {
+		entry, uri, ok := strings.Cut(override, "=")
+		entry = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(entry), "."))
+		uri = strings.TrimSpace(uri)
+
+		if !ok || entry == "" {
+			return nil, fmt.Errorf("invalid bootstrap override “%s”, expected “entry=URL”", override)
+		}
+
+		if u, err := url.Parse(uri); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid URL in bootstrap override “%s”", override)
+		}
+
+		// an address is the network of that address alone
+		if ip := net.ParseIP(entry); ip != nil {
+			if ip.To4() != nil {
+				entry += "/32"
+			} else {
+				entry += "/128"
+			}
+		}
+
+		kind := bootstrapOverrideKind(entry)
+
+		i := slices.IndexFunc(services[kind], func(service [2][]string) bool {
+			return service[0][0] == entry
+		})
+
+		if i < 0 {
+			services[kind] = append(services[kind], [2][]string{{entry}, {uri}})
+		} else {
+			services[kind][i][1] = append(services[kind][i][1], uri)
+		}
+	}
+
+	return services, nil
+}
+
+// bootstrapTransport is a RoundTripper decorator that answers the requests
+// of the bootstrap registries with the local files, and adds the overrides
+// to the registries before the IANA services. As the matches keep the first
+// service of the same length, the overrides replace the IANA entries that are
+// equally specific
+type bootstrapTransport struct {
+	transport    http.RoundTripper
+	bootstrapURI string
+	files        map[string]string
+	overrides    map[string][][2][]string
+}
+
+func (b *bootstrapTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var kind string
+	for _, k := range bootstrapKinds {
+		if req.URL.String() == fmt.Sprintf(b.bootstrapURI, k) {
+			kind = k
+			break
+		}
+	}
+
+	if kind == "" {
+		return b.transport.RoundTrip(req)
+	}
+
+	var (
+		resp *http.Response
+		err  error
+	)
+
+	if file, ok := b.files[kind]; ok {
+		tracef(req, "bootstrap registry read from the local file %s", file)
+		resp, err = readBootstrapFile(req, file)
+	} else {
+		resp, err = b.transport.RoundTrip(req)
+	}
+
+	if err != nil || resp.StatusCode != http.StatusOK || len(b.overrides[kind]) == 0 {
+		return resp, err
+	}
+
+	var registry serviceRegistry
+	err = json.NewDecoder(resp.Body).Decode(&registry)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("invalid bootstrap registry %s: %w", req.URL, err)
+	}
+
+	registry.Services = append(slices.Clone(b.overrides[kind]), registry.Services...)
+	tracef(req, "%d bootstrap overrides added before the entries of the registry", len(b.overrides[kind]))
+
+	body, err := json.Marshal(registry)
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = int64(len(body))
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
+
+// readBootstrapFile answers a bootstrap request with a local file
+func readBootstrapFile(req *http.Request, file string) (*http.Response, error) {
+	body, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
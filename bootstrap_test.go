@@ -0,0 +1,345 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/registrobr/rdap"
+	"github.com/registrobr/rdap-client/output"
+)
+
+func TestServiceRegistryMatchDomain(t *testing.T) {
+	registry := serviceRegistry{
+		Services: [][2][]string{
+			{{"br"}, {"http://rdap.registro.br/", "https://rdap.registro.br/"}},
+			{{"com.br", "net.br"}, {"https://rdap.example.com.br/"}},
+			{{"com"}, {"https://rdap.verisign.com/com/v1/"}},
+		},
+	}
+
+	data := []struct {
+		description string
+		fqdn        string
+		expected    []string
+	}{
+		{
+			description: "it should match the top level domain preferring HTTPS",
+			fqdn:        "a.dns.br",
+			expected:    []string{"https://rdap.registro.br/", "http://rdap.registro.br/"},
+		},
+		{
+			description: "it should match the longest entry",
+			fqdn:        "ns1.example.NET.br.",
+			expected:    []string{"https://rdap.example.com.br/"},
+		},
+		{
+			description: "it should not match partial labels",
+			fqdn:        "a.dns.xcom",
+		},
+	}
+
+	for _, item := range data {
+		if uris, _ := registry.matchDomain(item.fqdn); !reflect.DeepEqual(uris, item.expected) {
+			t.Errorf("%s: expected %v and got %v", item.description, item.expected, uris)
+		}
+	}
+}
+
+func TestParseBootstrapOverrides(t *testing.T) {
+	services, err := parseBootstrapOverrides([]string{
+		".BR=https://staging.rdap.registro.br/",
+		"br=http://staging.rdap.registro.br/",
+		"200.160.0.0/20=https://rdap.example.net/",
+		"2001:db8::/32=https://rdap.example.net/",
+		"64512-65534=https://rdap.example.net/",
+		"192.0.2.1=https://rdap.example.net/",
+		"2001:DB8::1=https://rdap.example.net/",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string][][2][]string{
+		"dns":  {{{"br"}, {"https://staging.rdap.registro.br/", "http://staging.rdap.registro.br/"}}},
+		"ipv4": {{{"200.160.0.0/20"}, {"https://rdap.example.net/"}}, {{"192.0.2.1/32"}, {"https://rdap.example.net/"}}},
+		"ipv6": {{{"2001:db8::/32"}, {"https://rdap.example.net/"}}, {{"2001:db8::1/128"}, {"https://rdap.example.net/"}}},
+		"asn":  {{{"64512-65534"}, {"https://rdap.example.net/"}}},
+	}
+
+	if !reflect.DeepEqual(services, expected) {
+		t.Errorf("expected %v and got %v", expected, services)
+	}
+
+	for _, override := range []string{"br", "=https://rdap.example.net/", "br=rdap.example.net"} {
+		if _, err := parseBootstrapOverrides([]string{override}); err == nil {
+			t.Errorf("expected an error for “%s”", override)
+		}
+	}
+}
+
+func TestBootstrapTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"version":"1.0","services":[[["br"],["https://rdap.registro.br/"]]]}`))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "asn.json")
+	if err := os.WriteFile(file, []byte(`{"version":"1.0","services":[[["1-10"],["https://rdap.example.net/"]]]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	client := http.Client{
+		Transport: &bootstrapTransport{
+			transport:    http.DefaultTransport,
+			bootstrapURI: server.URL + "/%s.json",
+			files:        map[string]string{"asn": file},
+			overrides: map[string][][2][]string{
+				"dns": {{{"br"}, {"https://staging.rdap.registro.br/"}}},
+			},
+		},
+	}
+
+	data := []struct {
+		description string
+		kind        string
+		fqdn        string
+		expected    []string
+	}{
+		{
+			description: "it should give precedence to the overrides",
+			kind:        "dns",
+			fqdn:        "nic.br",
+			expected:    []string{"https://staging.rdap.registro.br/"},
+		},
+		{
+			description: "it should read the local files",
+			kind:        "asn",
+			expected:    []string{"https://rdap.example.net/"},
+		},
+	}
+
+	for _, item := range data {
+		resp, err := client.Get(server.URL + "/" + item.kind + ".json")
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", item.description, err)
+		}
+
+		var registry serviceRegistry
+		err = json.NewDecoder(resp.Body).Decode(&registry)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", item.description, err)
+		}
+
+		if registry.Version != "1.0" || len(registry.Services) == 0 {
+			t.Fatalf("%s: unexpected registry %v", item.description, registry)
+		}
+
+		if uris := registry.Services[0][1]; !reflect.DeepEqual(uris, item.expected) {
+			t.Errorf("%s: expected %v and got %v", item.description, item.expected, uris)
+		}
+
+		if item.fqdn != "" {
+			if uris, _ := registry.matchDomain(item.fqdn); !reflect.DeepEqual(uris, item.expected) {
+				t.Errorf("%s: expected match %v and got %v", item.description, item.expected, uris)
+			}
+		}
+	}
+}
+
+func TestObjectTagRegistryMatchHandle(t *testing.T) {
+	registry := objectTagRegistry{
+		Services: [][3][]string{
+			{{"hostmaster@registro.br"}, {"NICBR"}, {"http://rdap.registro.br/", "https://rdap.registro.br/"}},
+			{{"info@arin.net"}, {"ARIN"}, {"https://rdap.arin.net/registry/"}},
+		},
+	}
+
+	data := []struct {
+		description  string
+		handle       string
+		expectedURIs []string
+		expectedTag  string
+	}{
+		{
+			description:  "it should match the tag preferring HTTPS",
+			handle:       "XXXX-NICBR",
+			expectedURIs: []string{"https://rdap.registro.br/", "http://rdap.registro.br/"},
+			expectedTag:  "NICBR",
+		},
+		{
+			description:  "it should match the tag after the last hyphen ignoring the case",
+			handle:       "ABC-123-arin",
+			expectedURIs: []string{"https://rdap.arin.net/registry/"},
+			expectedTag:  "ARIN",
+		},
+		{
+			description: "it should not match handles without a tag",
+			handle:      "ARIN",
+		},
+		{
+			description: "it should not match unknown tags",
+			handle:      "XXXX-RIPE",
+		},
+	}
+
+	for _, item := range data {
+		uris, tag := registry.matchHandle(item.handle)
+		if !reflect.DeepEqual(uris, item.expectedURIs) {
+			t.Errorf("%s: expected %v and got %v", item.description, item.expectedURIs, uris)
+		}
+
+		if tag != item.expectedTag {
+			t.Errorf("%s: expected tag %s and got %s", item.description, item.expectedTag, tag)
+		}
+	}
+}
+
+func TestServiceRegistryMatchIPAndASN(t *testing.T) {
+	registry := serviceRegistry{
+		Services: [][2][]string{
+			{{"200.0.0.0/8", "2001:1200::/23"}, {"https://rdap.lacnic.net/rdap/"}},
+			{{"200.160.0.0/20"}, {"https://rdap.registro.br/"}},
+			{{"1-1876", "27648-28671"}, {"https://rdap.lacnic.net/rdap/"}},
+		},
+	}
+
+	// the overrides come first, and only win the ties
+	overridden := serviceRegistry{
+		Services: [][2][]string{
+			{{"1-100000", "200.160.0.0/16", "200.160.0.0/20"}, {"https://staging.rdap.registro.br/"}},
+			{{"27648-28671", "28000-28100", "28050"}, {"https://rdap.lacnic.net/rdap/"}},
+			{{"200.160.0.0/20"}, {"https://rdap.registro.br/"}},
+			{{"0.0.0.0/0"}, {"https://rdap.example.net/"}},
+		},
+	}
+
+	data := []struct {
+		description string
+		match       func(string) string
+		value       string
+		expected    string
+	}{
+		{
+			description: "it should match the longest prefix of an address",
+			match:       registry.matchIP,
+			value:       "200.160.2.3",
+			expected:    "200.160.0.0/20",
+		},
+		{
+			description: "it should match the prefixes that cover a network",
+			match:       registry.matchIP,
+			value:       "200.160.0.0/16",
+			expected:    "200.0.0.0/8",
+		},
+		{
+			description: "it should match IPv6 addresses",
+			match:       registry.matchIP,
+			value:       "2001:12ff::1",
+			expected:    "2001:1200::/23",
+		},
+		{
+			description: "it should match the range of an AS number",
+			match:       registry.matchASN,
+			value:       "28000",
+			expected:    "27648-28671",
+		},
+		{
+			description: "it should not match AS numbers out of the ranges",
+			match:       registry.matchASN,
+			value:       "64512",
+		},
+		{
+			description: "it should prefer a smaller range to a wider override",
+			match:       overridden.matchASN,
+			value:       "28000",
+			expected:    "28000-28100",
+		},
+		{
+			description: "it should prefer the exact AS number",
+			match:       overridden.matchASN,
+			value:       "28050",
+			expected:    "28050",
+		},
+		{
+			description: "it should prefer the first of the equal prefixes",
+			match:       overridden.matchIP,
+			value:       "200.160.2.3",
+			expected:    "200.160.0.0/20",
+		},
+		{
+			description: "it should not match the prefixes of length zero",
+			match:       overridden.matchIP,
+			value:       "192.0.2.1",
+		},
+	}
+
+	for _, item := range data {
+		if entry := item.match(item.value); entry != item.expected {
+			t.Errorf("%s: expected “%s” and got “%s”", item.description, item.expected, entry)
+		}
+	}
+}
+
+func TestRegistryFetcherMatch(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/dns.json":
+			fmt.Fprintf(w, `{"version":"1.0","services":[[["br"],["%s/rdap/"]]]}`, server.URL)
+		case "/asn.json":
+			fmt.Fprintf(w, `{"version":"1.0","services":[[["1-10"],["%s/rdap/"]]]}`, server.URL)
+		default:
+			w.Header().Set("Content-Type", "application/rdap+json")
+			w.Write([]byte(`{}`))
+		}
+	}))
+	defer server.Close()
+
+	data := []struct {
+		description string
+		queryType   rdap.QueryType
+		queryValue  string
+		expected    *output.BootstrapMatch
+	}{
+		{
+			description: "it should record the entry of a domain query",
+			queryType:   rdap.QueryTypeDomain,
+			queryValue:  "nic.br",
+			expected:    &output.BootstrapMatch{Registry: "dns", Entry: "br"},
+		},
+		{
+			description: "it should record the entry of an AS number query",
+			queryType:   rdap.QueryTypeAutnum,
+			queryValue:  "5",
+			expected:    &output.BootstrapMatch{Registry: "asn", Entry: "1-10"},
+		},
+	}
+
+	for _, item := range data {
+		result := new(output.Result)
+
+		fetcher := (&registryFetcher{
+			fetcher:      &failoverFetcher{httpClient: http.DefaultClient},
+			httpClient:   http.DefaultClient,
+			bootstrapURI: server.URL + "/%s.json",
+		}).withResult(result)
+
+		resp, err := fetcher.Fetch(nil, item.queryType, item.queryValue, nil, nil)
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", item.description, err)
+		}
+		resp.Body.Close()
+
+		if !reflect.DeepEqual(result.Bootstrap, item.expected) {
+			t.Errorf("%s: expected %v and got %v", item.description, item.expected, result.Bootstrap)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gregjones/httpcache"
+)
+
+const (
+	// headerFetchedAt and headerRequestURL are stored along with the cached
+	// responses, to tell how old they are and what was requested
+	headerFetchedAt  = "X-Rdap-Fetched-At"
+	headerRequestURL = "X-Rdap-Request-Url"
+)
+
+var (
+	// errNotCached is returned in offline mode for the responses that aren't
+	// in the cache
+	errNotCached = errors.New("response not found in the cache (offline mode)")
+
+	// errOffline is returned in offline mode when the cache would need to
+	// reach the network
+	errOffline = errors.New("network access disabled (offline mode)")
+)
+
+// offlineTransport replaces the network below the cache in offline mode
+type offlineTransport struct{}
+
+func (offlineTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return nil, errOffline
+}
+
+// cacheStamp is a RoundTripper decorator placed below the cache, that adds to
+// the responses from the network the headers stored along with them. The
+// bodies the cache may drop are read before they reach it. The not
+// found responses without caching directives receive a short lifetime, so
+// repeated queries for missing objects are also answered from the cache
+type cacheStamp struct {
+	transport   http.RoundTripper
+	negativeTTL time.Duration
+}
+
+func (c *cacheStamp) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := c.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// the cache drops without closing the bodies of the revalidations and
+	// of the server errors answered with stale responses, what would keep
+	// the concurrency slots of the requests, so they are read in advance
+	if resp.StatusCode == http.StatusNotModified || resp.StatusCode >= http.StatusInternalServerError {
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	resp.Header.Set(headerFetchedAt, time.Now().UTC().Format(time.RFC3339))
+	resp.Header.Set(headerRequestURL, req.URL.String())
+
+	if resp.StatusCode == http.StatusNotFound && c.negativeTTL > 0 &&
+		resp.Header.Get("Cache-Control") == "" && resp.Header.Get("Expires") == "" {
+
+		resp.Header.Set("Cache-Control", fmt.Sprintf("max-age=%d", int(c.negativeTTL.Seconds())))
+	}
+
+	return resp, nil
+}
+
+// cachePolicy is a RoundTripper decorator placed above the cache, that
+// applies the freshness chosen by the user to the requests. A zero maxAge
+// keeps the lifetime defined by the server, refresh always revalidates the
+// stored responses and offline answers only with the stored responses, of
+// any age. The responses to the requests with credentials are stored apart
+// for each identity, so they are never served to other users nor to the
+// requests without credentials
+type cachePolicy struct {
+	transport http.RoundTripper
+	store     *credentialStore
+	maxAge    time.Duration
+	refresh   bool
+	offline   bool
+}
+
+func (c *cachePolicy) RoundTrip(req *http.Request) (*http.Response, error) {
+	identity, err := c.identity(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var cacheControl string
+
+	switch {
+	case c.offline:
+		cacheControl = "only-if-cached"
+	case c.refresh:
+		cacheControl = "no-cache"
+	case c.maxAge > 0 && req.Header.Get("Cache-Control") == "":
+		// the requests that already have caching directives, like the
+		// reloads of the bootstrap files, are kept
+		cacheControl = fmt.Sprintf("max-age=%d", int(c.maxAge.Seconds()))
+	}
+
+	if cacheControl != "" || identity != "" {
+		req = req.Clone(req.Context())
+	}
+
+	if cacheControl != "" {
+		req.Header.Set("Cache-Control", cacheControl)
+	}
+
+	// the cache key is the URL, and its fragment isn't sent to the server
+	if identity != "" {
+		req.URL.Fragment = "auth=" + identity
+	}
+
+	resp, err := c.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.offline && !fromCache(resp) {
+		// the cache answers the misses of only-if-cached requests with a
+		// gateway timeout
+		resp.Body.Close()
+		return nil, errNotCached
+	}
+
+	// the cache only stores a response when its body is read to the end, what
+	// the JSON decoders and the RDAP client for the not found responses don't
+	// always do
+	if !fromCache(resp) {
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	return resp, nil
+}
+
+// identity returns the identity of the credentials sent with the request by
+// the transport below the cache, or an empty string when there's none
+func (c *cachePolicy) identity(req *http.Request) (string, error) {
+	if c.store == nil || req.URL.Scheme != "https" {
+		return "", nil
+	}
+
+	host := strings.ToLower(req.URL.Hostname())
+
+	creds, err := c.store.lookup(host)
+	if creds == nil || err != nil {
+		return "", err
+	}
+
+	return creds.identity(host), nil
+}
+
+func fromCache(resp *http.Response) bool {
+	return resp.Header.Get(httpcache.XFromCache) == "1"
+}
+
+// fetchedAt returns when a response was retrieved from the network, that is
+// the current time for the responses that didn't come from the cache
+func fetchedAt(resp *http.Response) time.Time {
+	if date, err := time.Parse(time.RFC3339, resp.Header.Get(headerFetchedAt)); err == nil {
+		return date
+	}
+
+	if date, err := http.ParseTime(resp.Header.Get("Date")); err == nil {
+		return date
+	}
+
+	return time.Now()
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli"
+)
+
+// cacheEntry is a response stored by the disk cache, in a file named after
+// the MD5 hash of the requested URL
+type cacheEntry struct {
+	Key       string
+	Path      string
+	Size      int64
+	URL       string
+	Status    int
+	FetchedAt time.Time
+
+	// ExpiresAt is zero when the response has no lifetime, so it is always
+	// revalidated
+	ExpiresAt time.Time
+
+	// Err is why the file couldn't be read as a response, when only the key,
+	// the path and the size are known
+	Err error
+
+	response []byte
+}
+
+var cacheKeyRX = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// cacheKey returns the name of the file where the disk cache stores the
+// response of a URL
+func cacheKey(uri string) string {
+	sum := md5.Sum([]byte(uri))
+	return hex.EncodeToString(sum[:])
+}
+
+func readCacheEntry(dir, key string) (*cacheEntry, error) {
+	entry := cacheEntry{
+		Key:  key,
+		Path: filepath.Join(dir, key),
+	}
+
+	var err error
+	if entry.response, err = os.ReadFile(entry.Path); err != nil {
+		return nil, err
+	}
+	entry.Size = int64(len(entry.response))
+
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(entry.response)), nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cache entry %s: %w", key, err)
+	}
+	resp.Body.Close()
+
+	entry.Status = resp.StatusCode
+	entry.URL = resp.Header.Get(headerRequestURL)
+	entry.FetchedAt = fetchedAt(resp)
+
+	date, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		date = entry.FetchedAt
+	}
+
+	if lifetime, ok := responseLifetime(resp.Header, date); ok {
+		entry.ExpiresAt = date.Add(lifetime)
+	}
+
+	return &entry, nil
+}
+
+// responseLifetime returns how long the response is fresh, from the max-age
+// directive or the Expires header, following the rules of the disk cache
+func responseLifetime(header http.Header, date time.Time) (time.Duration, bool) {
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		if name == "max-age" {
+			seconds, err := strconv.Atoi(value)
+			if err != nil {
+				return 0, false
+			}
+
+			return time.Duration(seconds) * time.Second, true
+		}
+	}
+
+	if expires, err := time.Parse(time.RFC1123, header.Get("Expires")); err == nil {
+		return expires.Sub(date), true
+	}
+
+	return 0, false
+}
+
+// readCacheEntries lists the responses stored in the cache directory, that
+// also keeps other files like the templates. The files that can't be read
+// as responses are listed last as invalid entries, so they don't hide the
+// others and can be purged
+func readCacheEntries(dir string) ([]*cacheEntry, error) {
+	files, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var entries []*cacheEntry
+	for _, file := range files {
+		if file.IsDir() || !cacheKeyRX.MatchString(file.Name()) {
+			continue
+		}
+
+		entry, err := readCacheEntry(dir, file.Name())
+		if err != nil {
+			entry = &cacheEntry{
+				Key:  file.Name(),
+				Path: filepath.Join(dir, file.Name()),
+				Err:  err,
+			}
+
+			if info, err := file.Info(); err == nil {
+				entry.Size = info.Size()
+			}
+		}
+
+		entries = append(entries, entry)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if (entries[i].Err == nil) != (entries[j].Err == nil) {
+			return entries[i].Err == nil
+		}
+
+		return entries[i].FetchedAt.After(entries[j].FetchedAt)
+	})
+
+	return entries, nil
+}
+
+func formatCacheTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+
+	return t.UTC().Format(time.RFC3339)
+}
+
+func formatCacheURL(uri string) string {
+	if uri == "" {
+		return "(unknown)"
+	}
+
+	return uri
+}
+
+var cacheCommand = cli.Command{
+	Name:  "cache",
+	Usage: "manage the responses stored in the cache directory",
+	Subcommands: []cli.Command{
+		{
+			Name:   "list",
+			Usage:  "list the cached responses, the most recent first",
+			Action: cacheListAction,
+		},
+		{
+			Name:      "show",
+			Usage:     "show a cached response, given its key or URL",
+			ArgsUsage: "KEY",
+			Action:    cacheShowAction,
+		},
+		{
+			Name:  "purge",
+			Usage: "remove the cached responses, and the invalid entries",
+			Flags: []cli.Flag{
+				cli.DurationFlag{
+					Name:  "older-than",
+					Usage: "only remove the responses fetched before this duration (e.g. “24h”)",
+				},
+			},
+			Action: cachePurgeAction,
+		},
+		{
+			Name:   "stats",
+			Usage:  "summarize the cached responses",
+			Action: cacheStatsAction,
+		},
+	},
+}
+
+func cacheEntriesOrExit(ctx *cli.Context) []*cacheEntry {
+	entries, err := readCacheEntries(globalContext(ctx).String("cache"))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	return entries
+}
+
+func cacheListAction(ctx *cli.Context) {
+	entries := cacheEntriesOrExit(ctx)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tSTATUS\tFETCHED\tEXPIRES\tSIZE\tURL")
+
+	for _, entry := range entries {
+		if entry.Err != nil {
+			fmt.Fprintf(os.Stderr, "warning: %v\n", entry.Err)
+			fmt.Fprintf(w, "%s\t-\t-\t-\t%d\t(invalid)\n", entry.Key, entry.Size)
+			continue
+		}
+
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%d\t%s\n", entry.Key, entry.Status,
+			formatCacheTime(entry.FetchedAt), formatCacheTime(entry.ExpiresAt),
+			entry.Size, formatCacheURL(entry.URL))
+	}
+
+	w.Flush()
+}
+
+func cacheShowAction(ctx *cli.Context) {
+	key := ctx.Args().First()
+	if key == "" {
+		cli.ShowSubcommandHelp(ctx)
+		os.Exit(1)
+	}
+
+	if !cacheKeyRX.MatchString(key) {
+		key = cacheKey(key)
+	}
+
+	entry, err := readCacheEntry(globalContext(ctx).String("cache"), key)
+	if os.IsNotExist(err) {
+		fmt.Fprintf(os.Stderr, "no cached response for %s\n", ctx.Args().First())
+		os.Exit(1)
+	} else if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("key:      %s\n", entry.Key)
+	fmt.Printf("url:      %s\n", formatCacheURL(entry.URL))
+	fmt.Printf("fetched:  %s\n", formatCacheTime(entry.FetchedAt))
+	fmt.Printf("expires:  %s\n", formatCacheTime(entry.ExpiresAt))
+	fmt.Printf("size:     %d\n\n", entry.Size)
+	os.Stdout.Write(entry.response)
+	fmt.Println()
+}
+
+func cachePurgeAction(ctx *cli.Context) {
+	olderThan := ctx.Duration("older-than")
+	entries := cacheEntriesOrExit(ctx)
+
+	removed := 0
+	for _, entry := range entries {
+		// the age of the invalid entries is unknown, and they are useless
+		if entry.Err == nil && olderThan > 0 && time.Since(entry.FetchedAt) < olderThan {
+			continue
+		}
+
+		if err := os.Remove(entry.Path); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		removed++
+	}
+
+	fmt.Printf("%d cached responses removed\n", removed)
+}
+
+func cacheStatsAction(ctx *cli.Context) {
+	entries := cacheEntriesOrExit(ctx)
+
+	var (
+		size           int64
+		fresh, invalid int
+		oldest, newest time.Time
+		now            = time.Now()
+	)
+
+	for _, entry := range entries {
+		size += entry.Size
+
+		if entry.Err != nil {
+			invalid++
+			continue
+		}
+
+		if entry.ExpiresAt.After(now) {
+			fresh++
+		}
+
+		if oldest.IsZero() || entry.FetchedAt.Before(oldest) {
+			oldest = entry.FetchedAt
+		}
+
+		if entry.FetchedAt.After(newest) {
+			newest = entry.FetchedAt
+		}
+	}
+
+	fmt.Printf("entries:  %d\n", len(entries))
+	fmt.Printf("fresh:    %d\n", fresh)
+	fmt.Printf("expired:  %d\n", len(entries)-fresh-invalid)
+	fmt.Printf("invalid:  %d\n", invalid)
+	fmt.Printf("size:     %d\n", size)
+	fmt.Printf("oldest:   %s\n", formatCacheTime(oldest))
+	fmt.Printf("newest:   %s\n", formatCacheTime(newest))
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReadCacheEntries(t *testing.T) {
+	dir := t.TempDir()
+
+	if err := os.Mkdir(filepath.Join(dir, "templates"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	uri := "https://rdap.registro.br/domain/nic.br"
+	response := "HTTP/1.1 200 OK\r\n" +
+		"Cache-Control: max-age=3600\r\n" +
+		"Content-Type: application/rdap+json\r\n" +
+		"Date: Sun, 18 Oct 2026 10:00:00 GMT\r\n" +
+		"X-Rdap-Fetched-At: 2026-10-18T10:00:01Z\r\n" +
+		"X-Rdap-Request-Url: " + uri + "\r\n" +
+		"\r\n" +
+		"{}"
+
+	if err := os.WriteFile(filepath.Join(dir, cacheKey(uri)), []byte(response), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// a corrupt entry doesn't hide the others
+	corrupt := cacheKey("https://rdap.registro.br/domain/corrupt.br")
+	if err := os.WriteFile(filepath.Join(dir, corrupt), []byte("garbage"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := readCacheEntries(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries and got %d", len(entries))
+	}
+
+	if invalid := entries[1]; invalid.Key != corrupt || invalid.Err == nil || invalid.Size != 7 {
+		t.Errorf("expected the invalid entry last and got %+v", invalid)
+	}
+
+	entry := entries[0]
+
+	if entry.Err != nil {
+		t.Fatalf("unexpected error %v", entry.Err)
+	}
+
+	if entry.URL != uri {
+		t.Errorf("expected URL %s and got %s", uri, entry.URL)
+	}
+
+	if entry.Status != 200 {
+		t.Errorf("expected status 200 and got %d", entry.Status)
+	}
+
+	if expected := time.Date(2026, 10, 18, 10, 0, 1, 0, time.UTC); !entry.FetchedAt.Equal(expected) {
+		t.Errorf("expected fetch time %s and got %s", expected, entry.FetchedAt)
+	}
+
+	if expected := time.Date(2026, 10, 18, 11, 0, 0, 0, time.UTC); !entry.ExpiresAt.Equal(expected) {
+		t.Errorf("expected expiration %s and got %s", expected, entry.ExpiresAt)
+	}
+
+	if entry.Size != int64(len(response)) {
+		t.Errorf("expected size %d and got %d", len(response), entry.Size)
+	}
+}
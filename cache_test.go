@@ -0,0 +1,257 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gregjones/httpcache"
+)
+
+func TestCachePolicy(t *testing.T) {
+	var requests int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+
+		switch r.URL.Path {
+		case "/domain/cached.br":
+			w.Header().Set("Cache-Control", "max-age=60")
+		case "/domain/missing.br":
+			w.WriteHeader(http.StatusNotFound)
+		}
+
+		w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	data := []struct {
+		description       string
+		path              string
+		maxAge            time.Duration
+		refresh           bool
+		negativeTTL       time.Duration
+		expectedFromCache bool
+		expectedRequests  int32
+	}{
+		{
+			description:       "it should cache the responses with a lifetime",
+			path:              "/domain/cached.br",
+			expectedFromCache: true,
+			expectedRequests:  1,
+		},
+		{
+			description:      "it should revalidate on refresh",
+			path:             "/domain/cached.br",
+			refresh:          true,
+			expectedRequests: 2,
+		},
+		{
+			description:      "it should not use responses without a lifetime",
+			path:             "/domain/example.br",
+			expectedRequests: 2,
+		},
+		{
+			description:       "it should replace the lifetime with the maximum age",
+			path:              "/domain/example.br",
+			maxAge:            time.Hour,
+			expectedFromCache: true,
+			expectedRequests:  1,
+		},
+		{
+			description:       "it should cache the not found responses",
+			path:              "/domain/missing.br",
+			negativeTTL:       time.Minute,
+			expectedFromCache: true,
+			expectedRequests:  1,
+		},
+		{
+			description:      "it should not cache the not found responses without a negative TTL",
+			path:             "/domain/missing.br",
+			expectedRequests: 2,
+		},
+	}
+
+	for _, item := range data {
+		atomic.StoreInt32(&requests, 0)
+
+		transport := httpcache.NewMemoryCacheTransport()
+		transport.Transport = &cacheStamp{
+			transport:   http.DefaultTransport,
+			negativeTTL: item.negativeTTL,
+		}
+
+		client := http.Client{
+			Transport: &cachePolicy{
+				transport: transport,
+				maxAge:    item.maxAge,
+				refresh:   item.refresh,
+			},
+		}
+
+		var resp *http.Response
+		for i := 0; i < 2; i++ {
+			var err error
+			if resp, err = client.Get(server.URL + item.path); err != nil {
+				t.Fatalf("%s: unexpected error %v", item.description, err)
+			}
+
+			resp.Body.Close()
+		}
+
+		if fromCache(resp) != item.expectedFromCache {
+			t.Errorf("%s: expected from cache %t", item.description, item.expectedFromCache)
+		}
+
+		if item.expectedFromCache && time.Since(fetchedAt(resp)) > time.Minute {
+			t.Errorf("%s: unexpected fetch time %s", item.description, fetchedAt(resp))
+		}
+
+		if requests != item.expectedRequests {
+			t.Errorf("%s: expected %d requests and got %d", item.description, item.expectedRequests, requests)
+		}
+	}
+}
+
+func TestCachePolicyOffline(t *testing.T) {
+	var requests int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Cache-Control", "max-age=1")
+		w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	cache := httpcache.NewMemoryCache()
+
+	online := httpcache.NewTransport(cache)
+	online.Transport = &cacheStamp{transport: http.DefaultTransport}
+
+	offline := httpcache.NewTransport(cache)
+	offline.Transport = &cacheStamp{transport: offlineTransport{}}
+
+	onlineClient := http.Client{Transport: &cachePolicy{transport: online}}
+	offlineClient := http.Client{Transport: &cachePolicy{transport: offline, offline: true}}
+
+	resp, err := onlineClient.Get(server.URL + "/domain/example.br")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	// the stale responses are also used in offline mode
+	time.Sleep(1100 * time.Millisecond)
+
+	resp, err = offlineClient.Get(server.URL + "/domain/example.br")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	resp.Body.Close()
+
+	if !fromCache(resp) {
+		t.Error("expected a response from the cache")
+	}
+
+	if _, err := offlineClient.Get(server.URL + "/domain/missing.br"); !errors.Is(err, errNotCached) {
+		t.Errorf("expected error %v and got %v", errNotCached, err)
+	}
+
+	if requests != 1 {
+		t.Errorf("expected 1 request and got %d", requests)
+	}
+}
+
+func TestCachePolicyCredentials(t *testing.T) {
+	var requests int32
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Write([]byte(r.Header.Get("Authorization")))
+	}))
+	defer server.Close()
+
+	cache := httpcache.NewMemoryCache()
+
+	newClient := func(store *credentialStore) *http.Client {
+		transport := httpcache.NewTransport(cache)
+		transport.Transport = &cacheStamp{
+			transport: &hostTransport{transport: server.Client().Transport, store: store},
+		}
+
+		return &http.Client{Transport: &cachePolicy{transport: transport, store: store}}
+	}
+
+	newStore := func(username string) *credentialStore {
+		return newCredentialStore([]hostConfig{{
+			pattern:     "127.0.0.1",
+			credentials: credentials{username: username, password: "secret"},
+		}}, "")
+	}
+
+	var (
+		anonymous = newClient(newCredentialStore(nil, ""))
+		alice     = newClient(newStore("alice"))
+		bob       = newClient(newStore("bob"))
+	)
+
+	data := []struct {
+		description       string
+		client            *http.Client
+		expectedFromCache bool
+		expectedAuth      bool
+	}{
+		{
+			description: "it should store the public response",
+			client:      anonymous,
+		},
+		{
+			description:  "it should not serve the public response to the requests with credentials",
+			client:       alice,
+			expectedAuth: true,
+		},
+		{
+			description:       "it should serve the public response to the requests without credentials",
+			client:            anonymous,
+			expectedFromCache: true,
+		},
+		{
+			description:       "it should serve the authenticated response to the same user",
+			client:            alice,
+			expectedFromCache: true,
+			expectedAuth:      true,
+		},
+		{
+			description:  "it should not serve the authenticated response to other users",
+			client:       bob,
+			expectedAuth: true,
+		},
+	}
+
+	for _, item := range data {
+		resp, err := item.client.Get(server.URL + "/domain/example.br")
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", item.description, err)
+		}
+
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if fromCache(resp) != item.expectedFromCache {
+			t.Errorf("%s: expected from cache %t", item.description, item.expectedFromCache)
+		}
+
+		if (len(body) > 0) != item.expectedAuth {
+			t.Errorf("%s: unexpected authorization “%s”", item.description, body)
+		}
+	}
+
+	if requests != 3 {
+		t.Errorf("expected 3 requests and got %d", requests)
+	}
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/urfave/cli"
+)
+
+// profile stores the settings of the configuration file for a profile,
+// merged with the settings of the top level
+type profile struct {
+	// options are the default values of the global options, by their names
+	options map[string][]string
+	hosts   []hostConfig
+}
+
+// hostConfig stores the headers and the credentials sent to the hosts
+// matching a pattern, like “rdap.registro.br” or “*.arin.net”. The helper is
+// the command that gives the credentials, when they aren't in the file
+type hostConfig struct {
+	pattern     string
+	header      http.Header
+	credentials credentials
+	helper      string
+}
+
+// hostTransport is a RoundTripper decorator that adds the headers of the
+// first matching host and the credentials of the host to each request. As it
+// is placed below the redirects, they are never sent to other hosts, and the
+// credentials are only sent over HTTPS
+type hostTransport struct {
+	transport http.RoundTripper
+	store     *credentialStore
+}
+
+func (h *hostTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := strings.ToLower(req.URL.Hostname())
+	hc := h.store.match(host)
+
+	var creds *credentials
+	if req.URL.Scheme == "https" {
+		var err error
+		if creds, err = h.store.lookup(host); err != nil {
+			return nil, err
+		}
+	}
+
+	if hc == nil && creds == nil {
+		return h.transport.RoundTrip(req)
+	}
+
+	req = req.Clone(req.Context())
+	if hc != nil {
+		for name, values := range hc.header {
+			req.Header[name] = values
+		}
+	}
+
+	if creds != nil {
+		tracef(req, "%s credentials of %s sent", creds.scheme(), host)
+		creds.apply(req)
+	}
+
+	resp, err := h.transport.RoundTrip(req)
+	if resp != nil && creds != nil {
+		resp.Header.Set(headerAuthentication, creds.scheme())
+	}
+
+	return resp, err
+}
+
+func defaultConfigFile() string {
+	return filepath.Join(os.Getenv("HOME"), ".config", "rdap-client", "config")
+}
+
+// readProfile reads the profile from the configuration file. A missing
+// file is only an error when it was chosen by the user
+func readProfile(ctx *cli.Context) (*profile, error) {
+	var (
+		file = ctx.String("config")
+		name = ctx.String("profile")
+	)
+
+	f, err := os.Open(file)
+	if os.IsNotExist(err) && !ctx.IsSet("config") && name == "" {
+		return &profile{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return parseConfig(f, file, name, ctx.App.Flags)
+}
+
+// parseConfig reads a configuration file with the global options at the
+// top level, followed by the sections of the profiles and of the hosts:
+//
+//	output-type = jsonl
+//
+//	[profile staging]
+//	bootstrap-override = .br=https://staging.rdap.registro.br/
+//
+//	[profile staging host staging.rdap.registro.br]
+//	header = X-Api-Key: secret
+//	rate-limit = 2/s
+//
+//	[host *.arin.net]
+//	max-concurrent = 2
+//
+// The options of the named profile replace the same options of the top
+// level, and its hosts are matched first. Lines starting with “#” or “;”
+// are comments
+func parseConfig(r io.Reader, file, name string, flags []cli.Flag) (*profile, error) {
+	var (
+		top, named = newProfile(), newProfile()
+		found      bool
+		section    *profile
+		host       *hostConfig
+		lineNumber int
+		names      = flagNames(flags)
+	)
+
+	section = top
+	scanner := bufio.NewScanner(r)
+
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+
+		fail := func(format string, a ...any) error {
+			return fmt.Errorf("%s:%d: %s", file, lineNumber, fmt.Sprintf(format, a...))
+		}
+
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			fields := strings.Fields(strings.Trim(line, "[]"))
+			section, host = newProfile(), nil
+
+			var pattern string
+			switch {
+			case len(fields) == 2 && fields[0] == "profile":
+			case len(fields) == 2 && fields[0] == "host":
+				pattern = strings.ToLower(fields[1])
+			case len(fields) == 4 && fields[0] == "profile" && fields[2] == "host":
+				pattern = strings.ToLower(fields[3])
+			default:
+				return nil, fail("invalid section “%s”, expected “[profile NAME]”, “[host PATTERN]” or “[profile NAME host PATTERN]”", line)
+			}
+
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fail("invalid host pattern “%s”: %s", pattern, err)
+			}
+
+			// the sections of the other profiles are only validated
+			if fields[0] != "profile" {
+				section = top
+			} else if fields[1] == name {
+				section, found = named, true
+			}
+
+			if pattern != "" {
+				section.hosts = append(section.hosts, hostConfig{pattern: pattern, header: make(http.Header)})
+				host = &section.hosts[len(section.hosts)-1]
+			}
+
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if !ok || key == "" {
+			return nil, fail("invalid line “%s”, expected “name = value”", line)
+		}
+
+		if host != nil {
+			if err := section.setHost(host, key, value); err != nil {
+				return nil, fail("%s", err)
+			}
+
+			continue
+		}
+
+		option, ok := names[key]
+		if !ok {
+			return nil, fail("unknown option “%s”", key)
+		}
+
+		if _, isBool := option.flag.(cli.BoolFlag); isBool {
+			if _, err := strconv.ParseBool(value); err != nil {
+				return nil, fail("invalid boolean “%s” for option “%s”", value, key)
+			}
+		}
+
+		section.options[option.name] = append(section.options[option.name], value)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if name != "" && !found {
+		return nil, fmt.Errorf("profile “%s” not found in %s", name, file)
+	}
+
+	// the rules of the hosts are matched in order, so the ones of the profile
+	// come first and the others are kept
+	for option, values := range named.options {
+		if slices.Contains(hostOptions, option) {
+			values = append(values, top.options[option]...)
+		}
+
+		top.options[option] = values
+	}
+
+	named.options = top.options
+	named.hosts = append(named.hosts, top.hosts...)
+	return named, nil
+}
+
+// hostOptions are the options with rules for the hosts matching a pattern,
+// also set by the host sections
+var hostOptions = []string{"rate-limit", "max-concurrent", "pin", "proxy"}
+
+func newProfile() *profile {
+	return &profile{options: make(map[string][]string)}
+}
+
+// setHost stores a setting of a host section. The limits, the pins and the
+// proxy of the host become options of the profile
+func (p *profile) setHost(host *hostConfig, key, value string) error {
+	switch key {
+	case "header":
+		name, content, ok := strings.Cut(value, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return fmt.Errorf("invalid header “%s”, expected “Name: value”", value)
+		}
+
+		host.header.Add(strings.TrimSpace(name), strings.TrimSpace(content))
+	case "username":
+		host.credentials.username = value
+	case "password":
+		host.credentials.password = value
+	case "token":
+		host.credentials.token = value
+	case "credential-helper":
+		host.helper = value
+	case "rate-limit", "max-concurrent", "pin", "proxy":
+		p.options[key] = append(p.options[key], host.pattern+"="+value)
+	default:
+		return fmt.Errorf("unknown host setting “%s”, expected “header”, “username”, “password”, “token”, “credential-helper”, “rate-limit”, “max-concurrent”, “pin” or “proxy”", key)
+	}
+
+	return nil
+}
+
+type flagName struct {
+	name string
+	flag cli.Flag
+}
+
+// flagNames maps every name of the global options, including the short
+// ones, to the option. The options that choose the profile are left out
+func flagNames(flags []cli.Flag) map[string]flagName {
+	names := make(map[string]flagName)
+
+	for _, flag := range flags {
+		aliases := strings.Split(flag.GetName(), ",")
+		name := strings.TrimSpace(aliases[0])
+
+		if name == "config" || name == "profile" {
+			continue
+		}
+
+		for _, alias := range aliases {
+			names[strings.TrimSpace(alias)] = flagName{name: name, flag: flag}
+		}
+	}
+
+	return names
+}
+
+// applyProfile sets the global options that weren't given in the command
+// line or in the environment with the values of the profile, and keeps the
+// settings of the hosts in the application metadata
+func applyProfile(ctx *cli.Context, p *profile) error {
+	for name, values := range p.options {
+		if ctx.IsSet(name) {
+			continue
+		}
+
+		for _, value := range values {
+			if err := ctx.Set(name, value); err != nil {
+				return fmt.Errorf("invalid value “%s” for option “%s” in the configuration: %w", value, name, err)
+			}
+		}
+	}
+
+	ctx.App.Metadata["hosts"] = p.hosts
+	return nil
+}
+
+// configuredHosts returns the settings of the hosts of the profile
+func configuredHosts(ctx *cli.Context) []hostConfig {
+	hosts, _ := ctx.App.Metadata["hosts"].([]hostConfig)
+	return hosts
+}
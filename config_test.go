@@ -0,0 +1,195 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/urfave/cli"
+)
+
+var configFlags = []cli.Flag{
+	cli.StringFlag{Name: "cache"},
+	cli.StringFlag{Name: "output-type,o"},
+	cli.StringSliceFlag{Name: "host,H"},
+	cli.StringSliceFlag{Name: "rate-limit"},
+	cli.BoolFlag{Name: "no-notices"},
+}
+
+const configFile = `
+# shared by every profile
+output-type = jsonl
+cache = /tmp/rdap
+
+[host *.registro.br]
+rate-limit = 5/s
+
+[profile staging]
+o = default
+H = https://staging.rdap.registro.br
+H = https://mirror.rdap.registro.br
+
+[profile staging host staging.rdap.registro.br]
+header = X-Api-Key: secret
+username = joe
+password = s3cr3t
+rate-limit = 1/s
+
+[profile lab]
+no-notices = true
+`
+
+func TestParseConfig(t *testing.T) {
+	p, err := parseConfig(strings.NewReader(configFile), "config", "staging", configFlags)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedOptions := map[string][]string{
+		"output-type": {"default"},
+		"cache":       {"/tmp/rdap"},
+		"host":        {"https://staging.rdap.registro.br", "https://mirror.rdap.registro.br"},
+		"rate-limit":  {"staging.rdap.registro.br=1/s", "*.registro.br=5/s"},
+	}
+
+	if !reflect.DeepEqual(p.options, expectedOptions) {
+		t.Errorf("expected options %v and got %v", expectedOptions, p.options)
+	}
+
+	expectedHosts := []hostConfig{
+		{
+			pattern:     "staging.rdap.registro.br",
+			header:      http.Header{"X-Api-Key": {"secret"}},
+			credentials: credentials{username: "joe", password: "s3cr3t"},
+		},
+		{
+			pattern: "*.registro.br",
+			header:  http.Header{},
+		},
+	}
+
+	if !reflect.DeepEqual(p.hosts, expectedHosts) {
+		t.Errorf("expected hosts %v and got %v", expectedHosts, p.hosts)
+	}
+}
+
+func TestParseConfigErrors(t *testing.T) {
+	data := []struct {
+		description string
+		config      string
+		profile     string
+		expected    string
+	}{
+		{
+			description: "it should reject unknown options",
+			config:      "\ncolor = true\n",
+			expected:    "config:2: unknown option “color”",
+		},
+		{
+			description: "it should reject invalid booleans",
+			config:      "no-notices = maybe\n",
+			expected:    "config:1: invalid boolean “maybe” for option “no-notices”",
+		},
+		{
+			description: "it should reject invalid sections",
+			config:      "[server rdap.registro.br]\n",
+			expected:    "config:1: invalid section “[server rdap.registro.br]”, expected “[profile NAME]”, “[host PATTERN]” or “[profile NAME host PATTERN]”",
+		},
+		{
+			description: "it should reject unknown host settings",
+			config:      "[host rdap.registro.br]\ncookie = x\n",
+			expected:    "config:2: unknown host setting “cookie”, expected “header”, “username”, “password”, “token”, “credential-helper”, “rate-limit”, “max-concurrent”, “pin” or “proxy”",
+		},
+		{
+			description: "it should report missing profiles",
+			config:      configFile,
+			profile:     "production",
+			expected:    "profile “production” not found in config",
+		},
+	}
+
+	for _, item := range data {
+		_, err := parseConfig(strings.NewReader(item.config), "config", item.profile, configFlags)
+		if err == nil || err.Error() != item.expected {
+			t.Errorf("%s: expected error “%s” and got “%v”", item.description, item.expected, err)
+		}
+	}
+}
+
+func TestHostTransport(t *testing.T) {
+	var header http.Header
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+	})
+
+	server := httptest.NewTLSServer(handler)
+	defer server.Close()
+
+	plainServer := httptest.NewServer(handler)
+	defer plainServer.Close()
+
+	data := []struct {
+		description           string
+		url                   string
+		pattern               string
+		expectedKey           string
+		expectedToken         string
+		expectedAuthorization string
+	}{
+		{
+			description:           "it should send the headers and the credentials to the matching hosts",
+			url:                   server.URL,
+			pattern:               "127.0.0.*",
+			expectedKey:           "secret",
+			expectedToken:         "Bearer t0k3n",
+			expectedAuthorization: "bearer",
+		},
+		{
+			description: "it should not send them to other hosts",
+			url:         server.URL,
+			pattern:     "rdap.registro.br",
+		},
+		{
+			description: "it should not send the credentials over plain HTTP",
+			url:         plainServer.URL,
+			pattern:     "127.0.0.*",
+			expectedKey: "secret",
+		},
+	}
+
+	for _, item := range data {
+		hosts := []hostConfig{{
+			pattern:     item.pattern,
+			header:      http.Header{"X-Api-Key": {"secret"}},
+			credentials: credentials{token: "t0k3n"},
+		}}
+
+		client := http.Client{
+			Transport: &hostTransport{
+				transport: server.Client().Transport,
+				store:     newCredentialStore(hosts, ""),
+			},
+		}
+
+		resp, err := client.Get(item.url)
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", item.description, err)
+		}
+		resp.Body.Close()
+
+		if key := header.Get("X-Api-Key"); key != item.expectedKey {
+			t.Errorf("%s: expected key “%s” and got “%s”", item.description, item.expectedKey, key)
+		}
+
+		if token := header.Get("Authorization"); token != item.expectedToken {
+			t.Errorf("%s: expected authorization “%s” and got “%s”", item.description, item.expectedToken, token)
+		}
+
+		if scheme := resp.Header.Get(headerAuthentication); scheme != item.expectedAuthorization {
+			t.Errorf("%s: expected authentication “%s” and got “%s”", item.description, item.expectedAuthorization, scheme)
+		}
+	}
+}
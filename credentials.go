@@ -0,0 +1,260 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"sync"
+)
+
+// headerAuthentication is stored in the responses to the authenticated
+// requests with the scheme of the credentials, so the cached responses still
+// tell the access they were served to
+const headerAuthentication = "X-Rdap-Authentication"
+
+// credentials authenticate the requests to a host with HTTP Basic
+// authentication, or with a bearer token when there's one
+type credentials struct {
+	username string
+	password string
+	token    string
+
+	// subject identifies the user of tokens that change, like the ones of a
+	// login, that are refreshed
+	subject string
+}
+
+func (c credentials) empty() bool {
+	return c.username == "" && c.token == ""
+}
+
+// scheme returns the authentication scheme of the credentials
+func (c credentials) scheme() string {
+	if c.token != "" {
+		return "bearer"
+	}
+
+	return "basic"
+}
+
+// identity tells apart the credentials of a host in the cache keys, without
+// revealing them. It is the user of the Basic authentication, the subject of
+// the token or else the token
+func (c credentials) identity(host string) string {
+	id := c.username
+	if c.subject != "" {
+		id = c.subject
+	} else if c.token != "" {
+		id = c.token
+	}
+
+	sum := sha256.Sum256([]byte(c.scheme() + " " + host + " " + id))
+	return hex.EncodeToString(sum[:8])
+}
+
+func (c credentials) apply(req *http.Request) {
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	} else {
+		req.SetBasicAuth(c.username, c.password)
+	}
+}
+
+// hostLocks serializes the work done for each host, like running a
+// credential helper or refreshing a token, without blocking the requests to
+// the other hosts
+type hostLocks struct {
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+// lock locks the host, returning the function that unlocks it
+func (h *hostLocks) lock(host string) func() {
+	h.mu.Lock()
+	if h.locks == nil {
+		h.locks = make(map[string]*sync.Mutex)
+	}
+
+	lock, ok := h.locks[host]
+	if !ok {
+		lock = new(sync.Mutex)
+		h.locks[host] = lock
+	}
+	h.mu.Unlock()
+
+	lock.Lock()
+	return lock.Unlock
+}
+
+// credentialStore finds the credentials of each host. They come from the
+// first host pattern of the configuration matching the host name, from the
+// environment, from the tokens of a login or from a credential helper, in
+// that order, and are kept for the next requests, except the tokens that may
+// expire
+type credentialStore struct {
+	hosts  []hostConfig
+	helper string
+	tokens *tokenStore
+
+	locks    hostLocks
+	mu       sync.Mutex
+	resolved map[string]*credentials
+}
+
+func newCredentialStore(hosts []hostConfig, helper string) *credentialStore {
+	return &credentialStore{
+		hosts:    hosts,
+		helper:   helper,
+		resolved: make(map[string]*credentials),
+	}
+}
+
+// match returns the first host of the configuration matching the host name
+func (s *credentialStore) match(host string) *hostConfig {
+	for i, hc := range s.hosts {
+		if ok, _ := path.Match(hc.pattern, host); ok {
+			return &s.hosts[i]
+		}
+	}
+
+	return nil
+}
+
+// lookup returns the credentials of the host, or nil when there's none. Only
+// the lookups of the same host wait for each other
+func (s *credentialStore) lookup(host string) (*credentials, error) {
+	unlock := s.locks.lock(host)
+	defer unlock()
+
+	s.mu.Lock()
+	creds, ok := s.resolved[host]
+	s.mu.Unlock()
+
+	if ok {
+		return creds, nil
+	}
+
+	creds, helper := s.configured(host)
+
+	// the tokens of a login aren't kept, as they may be refreshed
+	if creds == nil && s.tokens != nil {
+		if creds, err := s.tokens.lookup(host); creds != nil || err != nil {
+			return creds, err
+		}
+	}
+
+	if creds == nil && helper != "" {
+		var err error
+		if creds, err = runCredentialHelper(helper, host); err != nil {
+			return nil, err
+		}
+	}
+
+	s.mu.Lock()
+	s.resolved[host] = creds
+	s.mu.Unlock()
+
+	return creds, nil
+}
+
+// configured returns the credentials of the host given in the configuration
+// or in the environment, and the credential helper of the host
+func (s *credentialStore) configured(host string) (*credentials, string) {
+	helper := s.helper
+
+	if hc := s.match(host); hc != nil {
+		if !hc.credentials.empty() {
+			return &hc.credentials, ""
+		}
+
+		if hc.helper != "" {
+			helper = hc.helper
+		}
+	}
+
+	return envCredentials(host), helper
+}
+
+// envCredentials reads the credentials of the host from the variables
+// RDAP_TOKEN_<HOST>, or RDAP_USERNAME_<HOST> and RDAP_PASSWORD_<HOST>, where
+// the host is in upper case with the other characters replaced by “_”, like
+// RDAP_TOKEN_RDAP_REGISTRO_BR
+func envCredentials(host string) *credentials {
+	suffix := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, strings.ToUpper(host))
+
+	creds := credentials{
+		username: os.Getenv("RDAP_USERNAME_" + suffix),
+		password: os.Getenv("RDAP_PASSWORD_" + suffix),
+		token:    os.Getenv("RDAP_TOKEN_" + suffix),
+	}
+
+	if creds.empty() {
+		return nil
+	}
+
+	return &creds
+}
+
+// runCredentialHelper runs the helper command with the host name as the last
+// argument. The helper writes lines in the format “key=value”, with the keys
+// “username”, “password” or “token”, and writes nothing when it has no
+// credentials for the host
+func runCredentialHelper(helper, host string) (*credentials, error) {
+	args := strings.Fields(helper)
+	if len(args) == 0 {
+		return nil, nil
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(args[0], append(args[1:], host)...)
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			err = fmt.Errorf("%w: %s", err, message)
+		}
+		return nil, fmt.Errorf("credential helper for %s failed: %w", host, err)
+	}
+
+	var creds credentials
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		key, value, _ := strings.Cut(line, "=")
+
+		switch strings.TrimSpace(key) {
+		case "username":
+			creds.username = value
+		case "password":
+			creds.password = value
+		case "token":
+			creds.token = value
+		default:
+			return nil, fmt.Errorf("credential helper for %s returned an unknown key “%s”, expected “username”, “password” or “token”", host, key)
+		}
+	}
+
+	if creds.empty() {
+		return nil, nil
+	}
+
+	return &creds, nil
+}
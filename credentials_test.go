@@ -0,0 +1,111 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCredentialStore(t *testing.T) {
+	helper := filepath.Join(t.TempDir(), "helper")
+	script := "#!/bin/sh\n[ \"$2\" = rdap.registrar.example ] && printf 'username=%s\\npassword=p=ss\\n' \"$1\"\nexit 0\n"
+	if err := os.WriteFile(helper, []byte(script), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("RDAP_TOKEN_RDAP_REGISTRY_EXAMPLE", "env-token")
+
+	hosts := []hostConfig{
+		{pattern: "rdap.registro.br", credentials: credentials{token: "t0k3n"}},
+		{pattern: "*.registrar.example", helper: helper + " joe"},
+	}
+
+	data := []struct {
+		description string
+		host        string
+		expected    *credentials
+	}{
+		{
+			description: "it should use the credentials of the configuration",
+			host:        "rdap.registro.br",
+			expected:    &credentials{token: "t0k3n"},
+		},
+		{
+			description: "it should read the credentials from the environment",
+			host:        "rdap.registry.example",
+			expected:    &credentials{token: "env-token"},
+		},
+		{
+			description: "it should run the credential helper of the host",
+			host:        "rdap.registrar.example",
+			expected:    &credentials{username: "joe", password: "p=ss"},
+		},
+		{
+			description: "it should accept helpers without credentials for the host",
+			host:        "www.registrar.example",
+		},
+		{
+			description: "it should not send credentials to other hosts",
+			host:        "rdap.arin.net",
+		},
+	}
+
+	store := newCredentialStore(hosts, "")
+
+	for _, item := range data {
+		creds, err := store.lookup(item.host)
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", item.description, err)
+		}
+
+		if !reflect.DeepEqual(creds, item.expected) {
+			t.Errorf("%s: expected %v and got %v", item.description, item.expected, creds)
+		}
+	}
+
+	if _, err := newCredentialStore(nil, "false").lookup("rdap.registro.br"); err == nil {
+		t.Error("expected an error from the failing helper")
+	}
+}
+
+func TestCredentialStoreConcurrency(t *testing.T) {
+	dir := t.TempDir()
+	started := filepath.Join(dir, "started")
+
+	// the helper tells it started, and then takes its time
+	helper := filepath.Join(dir, "helper")
+	script := "#!/bin/sh\ntouch " + started + "\nsleep 2\necho token=slow\n"
+	if err := os.WriteFile(helper, []byte(script), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	store := newCredentialStore([]hostConfig{
+		{pattern: "rdap.registro.br", credentials: credentials{token: "s3cr3t"}},
+	}, helper)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		store.lookup("rdap.slow.example")
+	}()
+
+	for i := 0; i < 100; i++ {
+		if _, err := os.Stat(started); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	start := time.Now()
+	if creds, err := store.lookup("rdap.registro.br"); err != nil || creds == nil || creds.token != "s3cr3t" {
+		t.Errorf("unexpected credentials %v and error %v", creds, err)
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("the lookup waited %s for the helper of another host", elapsed)
+	}
+
+	<-done
+}
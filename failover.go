@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/registrobr/rdap"
+	"github.com/registrobr/rdap-client/output"
+	"github.com/registrobr/rdap/protocol"
+)
+
+// resultFetcher is implemented by the Fetchers that keep details of the query
+// in its result, returning a copy of the Fetcher for each query
+type resultFetcher interface {
+	withResult(result *output.Result) rdap.Fetcher
+}
+
+// failoverFetcher is a Fetcher that queries the RDAP servers in order, moving
+// to the next one only when a server can't be reached or fails with a 5xx
+// status. Any other answer, like not found, is kept. When result is set, the
+// servers that failed are kept in it
+type failoverFetcher struct {
+	httpClient *http.Client
+	result     *output.Result
+}
+
+func (f *failoverFetcher) withResult(result *output.Result) rdap.Fetcher {
+	fetcher := *f
+	fetcher.result = result
+	return &fetcher
+}
+
+func (f *failoverFetcher) Fetch(uris []string, queryType rdap.QueryType, queryValue string, header http.Header, queryString url.Values) (*http.Response, error) {
+	if len(uris) == 0 {
+		return nil, fmt.Errorf("no URIs defined to query")
+	}
+
+	var urls []string
+	for _, uri := range uris {
+		urls = append(urls, queryURL(uri, fmt.Sprintf("%s/%s", queryType, queryValue), queryString))
+	}
+
+	return f.fetchURLs(urls, header)
+}
+
+// fetchURLs sends the query to each URL until a server answers
+func (f *failoverFetcher) fetchURLs(urls []string, header http.Header) (resp *http.Response, err error) {
+	for i, u := range urls {
+		var failover bool
+		if resp, failover, err = fetchRDAP(f.httpClient, u, header); !failover || i == len(urls)-1 {
+			return resp, err
+		}
+
+		if f.result != nil {
+			f.result.Failover = append(f.result.Failover, output.FailedServer{
+				Server: u,
+				Error:  err.Error(),
+			})
+		}
+	}
+
+	return
+}
+
+// queryURL builds the URL of a query, given the base URL of the RDAP server
+// and the path of the query
+func queryURL(uri, path string, queryString url.Values) string {
+	if !strings.HasPrefix(uri, "http://") && !strings.HasPrefix(uri, "https://") {
+		uri = "http://" + uri
+	}
+
+	if pos := strings.Index(uri, "?"); pos != -1 {
+		uri = uri[:pos]
+	}
+
+	uri = fmt.Sprintf("%s/%s", strings.TrimRight(uri, "/"), path)
+
+	if q := queryString.Encode(); len(q) > 0 {
+		uri += "?" + q
+	}
+
+	return uri
+}
+
+// fetchRDAP sends the query to a single URL, handling the answer as the RDAP
+// library does: the response is returned along with ErrNotFound or
+// ErrForbidden, and the other errors of the server are decoded. It also
+// reports when the query should move to the next server
+func fetchRDAP(httpClient *http.Client, uri string, header http.Header) (resp *http.Response, failover bool, err error) {
+	req, err := http.NewRequest(http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if header != nil {
+		req.Header = header.Clone()
+	}
+
+	req.Header.Set("Accept", "application/rdap+json")
+	req.Header.Set("User-Agent", "registrobr-rdap")
+
+	// connection and TLS errors, and the transient failures that were already
+	// retried, may not happen in other servers
+	if resp, err = httpClient.Do(req); err != nil {
+		return nil, true, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusNotFound:
+		return resp, false, rdap.ErrNotFound
+	case http.StatusForbidden:
+		return resp, false, rdap.ErrForbidden
+	}
+
+	failover = resp.StatusCode >= http.StatusInternalServerError
+
+	contentType := strings.Split(resp.Header.Get("Content-Type"), ";")[0]
+	if contentType != "application/rdap+json" {
+		resp.Body.Close()
+		return nil, failover, fmt.Errorf("unexpected response: %d %s",
+			resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+
+		var responseErr protocol.Error
+		if err := json.NewDecoder(resp.Body).Decode(&responseErr); err != nil {
+			return nil, failover, err
+		}
+
+		return nil, failover, responseErr
+	}
+
+	return resp, false, nil
+}
+
+// errResolved stops the RDAP library before it sends the query
+var errResolved = errors.New("RDAP servers resolved")
+
+// httpDoer sends the HTTP requests, like http.Client and bootstrapResolver
+type httpDoer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// bootstrapResolver is given to rdap.NewBootstrapFetcher in place of the HTTP
+// client, to find the RDAP servers of a query with the bootstrap of the
+// library without sending it. The bootstrap registries are still retrieved
+// by httpClient, and the URLs of the query are kept in the order of the
+// library. The last registry is kept with what the cache detector found
+// about it, that is also the answer given to the library
+type bootstrapResolver struct {
+	httpClient    *http.Client
+	cacheDetector rdap.CacheDetector
+	urls          []string
+
+	registryURL string
+	registry    []byte
+	cached      bool
+}
+
+func (b *bootstrapResolver) Do(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("Accept") == "application/rdap+json" {
+		b.urls = append(b.urls, req.URL.String())
+		return nil, errResolved
+	}
+
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	b.registryURL = req.URL.String()
+	b.registry = body
+	b.cached = b.cacheDetector != nil && b.cacheDetector(resp)
+	return resp, nil
+}
+
+// detected is the cache detector given to the library, so the one of the
+// resolver runs once for each registry
+func (b *bootstrapResolver) detected(*http.Response) bool {
+	return b.cached
+}
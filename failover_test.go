@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/registrobr/rdap"
+	"github.com/registrobr/rdap-client/output"
+)
+
+func TestFailoverFetcher(t *testing.T) {
+	var requests int32
+
+	handler := func(status int) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requests, 1)
+			w.Header().Set("Content-Type", "application/rdap+json")
+			w.WriteHeader(status)
+			w.Write([]byte(`{"objectClassName":"domain","ldhName":"nic.br"}`))
+		}
+	}
+
+	failing := httptest.NewServer(handler(http.StatusInternalServerError))
+	defer failing.Close()
+
+	notFound := httptest.NewServer(handler(http.StatusNotFound))
+	defer notFound.Close()
+
+	ok := httptest.NewServer(handler(http.StatusOK))
+	defer ok.Close()
+
+	unreachable := httptest.NewServer(nil)
+	unreachable.Close()
+
+	data := []struct {
+		description      string
+		uris             []string
+		expectedErr      error
+		expectedServer   string
+		expectedFailover int
+		expectedRequests int32
+	}{
+		{
+			description:      "it should move to the next server on server errors",
+			uris:             []string{failing.URL, ok.URL},
+			expectedServer:   ok.URL,
+			expectedFailover: 1,
+			expectedRequests: 2,
+		},
+		{
+			description:      "it should move to the next server on connection errors",
+			uris:             []string{unreachable.URL, ok.URL},
+			expectedServer:   ok.URL,
+			expectedFailover: 1,
+			expectedRequests: 1,
+		},
+		{
+			description:      "it should keep the not found answers",
+			uris:             []string{notFound.URL, ok.URL},
+			expectedErr:      rdap.ErrNotFound,
+			expectedServer:   notFound.URL,
+			expectedRequests: 1,
+		},
+	}
+
+	for _, item := range data {
+		atomic.StoreInt32(&requests, 0)
+
+		result := &output.Result{}
+		fetcher := (&failoverFetcher{httpClient: http.DefaultClient}).withResult(result)
+
+		resp, err := fetcher.Fetch(item.uris, rdap.QueryTypeDomain, "nic.br", nil, nil)
+		if !errors.Is(err, item.expectedErr) {
+			t.Fatalf("%s: expected error %v and got %v", item.description, item.expectedErr, err)
+		}
+		resp.Body.Close()
+
+		if server := resp.Request.URL.String(); server != item.expectedServer+"/domain/nic.br" {
+			t.Errorf("%s: unexpected server %s", item.description, server)
+		}
+
+		if len(result.Failover) != item.expectedFailover {
+			t.Errorf("%s: expected %d failed servers and got %v", item.description, item.expectedFailover, result.Failover)
+		}
+
+		if requests != item.expectedRequests {
+			t.Errorf("%s: expected %d requests and got %d", item.description, item.expectedRequests, requests)
+		}
+	}
+}
@@ -9,6 +9,7 @@ require (
 	github.com/gregjones/httpcache v0.0.0-20190611155906-901d90724c79
 	github.com/registrobr/rdap v1.1.7
 	github.com/urfave/cli v1.22.17
+	golang.org/x/net v0.38.0
 )
 
 require (
@@ -16,6 +17,5 @@ require (
 	github.com/google/btree v0.0.0-20161217183710-316fb6d3f031 // indirect
 	github.com/peterbourgon/diskv v2.0.1-0.20160404093648-5dfcb07a075a+incompatible // indirect
 	github.com/russross/blackfriday/v2 v2.1.0 // indirect
-	golang.org/x/net v0.38.0 // indirect
 	golang.org/x/text v0.23.0 // indirect
 )
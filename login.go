@@ -0,0 +1,305 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/urfave/cli"
+)
+
+// tokenRefreshMargin is how long before its expiration a stored access
+// token is refreshed
+const tokenRefreshMargin = 30 * time.Second
+
+var loginCommand = cli.Command{
+	Name:      "login",
+	Usage:     "log in to an RDAP server with OpenID Connect (RFC 9560), sending the tokens with the next queries to the server",
+	ArgsUsage: "[SERVER]",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "issuer",
+			Usage: "OpenID provider, instead of the default one advertised by the server",
+		},
+		cli.StringFlag{
+			Name:  "client-id",
+			Usage: "client identifier registered in the OpenID provider",
+		},
+		cli.StringFlag{
+			Name:   "client-secret",
+			EnvVar: "RDAP_CLIENT_SECRET",
+			Usage:  "client secret registered in the OpenID provider, for confidential clients",
+		},
+		cli.StringFlag{
+			Name:  "scope",
+			Value: "openid",
+			Usage: "scopes requested to the OpenID provider",
+		},
+		cli.StringFlag{
+			Name:  "flow",
+			Usage: "“device” to log in from another device, or “code” to log in with the local browser (default: device when supported)",
+		},
+	},
+	Action: loginAction,
+}
+
+// loginAction logs in to the server given as argument, or to the first one
+// given with --host
+func loginAction(ctx *cli.Context) {
+	server := ctx.Args().First()
+	if hosts := globalContext(ctx).StringSlice("host"); server == "" && len(hosts) > 0 {
+		server = hosts[0]
+	}
+
+	if server == "" || ctx.String("client-id") == "" {
+		cli.ShowSubcommandHelp(ctx)
+		os.Exit(1)
+	}
+
+	if err := login(ctx, server); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func login(ctx *cli.Context, server string) error {
+	globalCtx := globalContext(ctx)
+
+	serverURL, err := url.Parse(server)
+	if err != nil || serverURL.Scheme != "https" || serverURL.Host == "" {
+		return fmt.Errorf("invalid server “%s”, expected an HTTPS URL", server)
+	}
+
+	tlsConfig, err := newTLSConfig(globalCtx)
+	if err != nil {
+		return err
+	}
+
+	proxy, err := newProxy(globalCtx)
+	if err != nil {
+		return err
+	}
+
+	httpClient := newDirectHTTPClient(globalCtx, newRateLimiter(nil, nil), tlsConfig, proxy)
+
+	issuer := ctx.String("issuer")
+	if issuer == "" {
+		if issuer, err = discoverIssuer(httpClient, server); err != nil {
+			return err
+		}
+	}
+
+	provider, err := discoverProvider(httpClient, issuer)
+	if err != nil {
+		return err
+	}
+
+	client := &oidcClient{
+		httpClient:   httpClient,
+		clientID:     ctx.String("client-id"),
+		clientSecret: ctx.String("client-secret"),
+		scope:        ctx.String("scope"),
+	}
+
+	var token *tokenResponse
+	switch flow := ctx.String("flow"); {
+	case flow == "device" || flow == "" && provider.DeviceAuthorizationEndpoint != "":
+		token, err = client.deviceFlow(provider, os.Stderr)
+	case flow == "code" || flow == "":
+		token, err = client.codeFlow(provider, func(authURL string) {
+			fmt.Fprintf(os.Stderr, "Open this URL in the browser to log in:\n\n  %s\n\n", authURL)
+		})
+	default:
+		return fmt.Errorf("invalid flow “%s”, expected “device” or “code”", flow)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	tokens := newTokenStore(globalCtx.String("cache"), httpClient)
+	host := strings.ToLower(serverURL.Hostname())
+
+	stored := &storedToken{
+		Server:        server,
+		Issuer:        provider.Issuer,
+		TokenEndpoint: provider.TokenEndpoint,
+		ClientID:      client.clientID,
+		ClientSecret:  client.clientSecret,
+	}
+	stored.update(token)
+
+	if err := tokens.save(host, stored); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "logged in to %s with %s\n", host, provider.Issuer)
+	return nil
+}
+
+// storedToken are the tokens of a server, with what's needed to refresh
+// them
+type storedToken struct {
+	Server        string    `json:"server"`
+	Issuer        string    `json:"issuer"`
+	TokenEndpoint string    `json:"tokenEndpoint"`
+	ClientID      string    `json:"clientId"`
+	ClientSecret  string    `json:"clientSecret,omitempty"`
+	AccessToken   string    `json:"accessToken"`
+	RefreshToken  string    `json:"refreshToken,omitempty"`
+	IDToken       string    `json:"idToken,omitempty"`
+	Expiry        time.Time `json:"expiry,omitempty"`
+}
+
+// update keeps the tokens of a token response. The refresh token is kept
+// when the provider doesn't rotate it
+func (s *storedToken) update(token *tokenResponse) {
+	s.AccessToken = token.AccessToken
+
+	if token.RefreshToken != "" {
+		s.RefreshToken = token.RefreshToken
+	}
+
+	if token.IDToken != "" {
+		s.IDToken = token.IDToken
+	}
+
+	s.Expiry = time.Time{}
+	if token.ExpiresIn > 0 {
+		s.Expiry = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	}
+}
+
+// subject identifies the user of the login, that is kept when the tokens are
+// refreshed: the issuer, the client and the subject of the ID token, or the
+// server when the ID token doesn't tell it
+func (s *storedToken) subject() string {
+	subject := s.Server
+
+	// the claims were checked by the provider that issued the token, they only
+	// tell the tokens apart here
+	if parts := strings.Split(s.IDToken, "."); len(parts) == 3 {
+		var claims struct {
+			Subject string `json:"sub"`
+		}
+
+		if payload, err := base64.RawURLEncoding.DecodeString(parts[1]); err == nil &&
+			json.Unmarshal(payload, &claims) == nil && claims.Subject != "" {
+
+			subject = claims.Subject
+		}
+	}
+
+	return s.Issuer + " " + s.ClientID + " " + subject
+}
+
+func (s *storedToken) expired() bool {
+	return !s.Expiry.IsZero() && time.Now().Add(tokenRefreshMargin).After(s.Expiry)
+}
+
+// tokenStore keeps the tokens of the logins in the “tokens” directory of the
+// cache, in a file for each server host, refreshing them when they expire.
+// In offline mode the expired tokens are kept, as they only identify the
+// cached responses
+type tokenStore struct {
+	dir        string
+	httpClient *http.Client
+	offline    bool
+
+	locks  hostLocks
+	mu     sync.Mutex
+	loaded map[string]*storedToken
+}
+
+func newTokenStore(cache string, httpClient *http.Client) *tokenStore {
+	return &tokenStore{
+		dir:        filepath.Join(cache, "tokens"),
+		httpClient: httpClient,
+		loaded:     make(map[string]*storedToken),
+	}
+}
+
+func (t *tokenStore) path(host string) string {
+	return filepath.Join(t.dir, host+".json")
+}
+
+func (t *tokenStore) save(host string, stored *storedToken) error {
+	if err := os.MkdirAll(t.dir, 0700); err != nil {
+		return err
+	}
+
+	content, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(t.path(host), content, 0600)
+}
+
+// lookup returns the access token of the host as credentials, or nil when
+// there was no login to the host. The tokens are read once, and are checked
+// on every request, so an expired token is refreshed even in a long batch.
+// The login must be repeated when that fails. Only the lookups of the same
+// host wait for a refresh
+func (t *tokenStore) lookup(host string) (*credentials, error) {
+	unlock := t.locks.lock(host)
+	defer unlock()
+
+	t.mu.Lock()
+	stored, ok := t.loaded[host]
+	t.mu.Unlock()
+
+	if !ok {
+		content, err := os.ReadFile(t.path(host))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+
+		if err == nil {
+			stored = new(storedToken)
+			if err := json.Unmarshal(content, stored); err != nil {
+				return nil, fmt.Errorf("invalid tokens of %s in %s: %w", host, t.path(host), err)
+			}
+		}
+
+		t.mu.Lock()
+		t.loaded[host] = stored
+		t.mu.Unlock()
+	}
+
+	if stored == nil {
+		return nil, nil
+	}
+
+	if stored.expired() && !t.offline {
+		if stored.RefreshToken == "" {
+			return nil, fmt.Errorf("the login to %s expired, run “rdap-client login %s” again", host, stored.Server)
+		}
+
+		client := &oidcClient{
+			httpClient:   t.httpClient,
+			clientID:     stored.ClientID,
+			clientSecret: stored.ClientSecret,
+		}
+
+		token, err := client.refresh(stored.TokenEndpoint, stored.RefreshToken)
+		if err != nil {
+			return nil, fmt.Errorf("the login to %s couldn't be refreshed, run “rdap-client login %s” again: %w", host, stored.Server, err)
+		}
+
+		stored.update(token)
+		if err := t.save(host, stored); err != nil {
+			return nil, err
+		}
+	}
+
+	return &credentials{token: stored.AccessToken, subject: stored.subject()}, nil
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+)
+
+// identityProvider is a stand-in OpenID provider that accepts the client
+// “rdap-client”, asking the device flow to wait once
+type identityProvider struct {
+	*httptest.Server
+	challenge string
+	polls     int
+	refreshes int
+}
+
+func newIdentityProvider() *identityProvider {
+	idp := new(identityProvider)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(oidcProvider{
+			Issuer:                      idp.URL,
+			AuthorizationEndpoint:       idp.URL + "/authorize",
+			TokenEndpoint:               idp.URL + "/token",
+			DeviceAuthorizationEndpoint: idp.URL + "/device",
+		})
+	})
+
+	mux.HandleFunc("/device", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"device_code":"d3v1c3","user_code":"ABCD-1234","verification_uri":"https://idp.example/device","expires_in":60,"interval":1}`)
+	})
+
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		idp.challenge = query.Get("code_challenge")
+
+		redirect := query.Get("redirect_uri") + "?code=c0d3&state=" + url.QueryEscape(query.Get("state"))
+		http.Redirect(w, r, redirect, http.StatusFound)
+	})
+
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("client_id") != "rdap-client" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error":"invalid_client"}`)
+			return
+		}
+
+		switch r.Form.Get("grant_type") {
+		case "urn:ietf:params:oauth:grant-type:device_code":
+			if idp.polls++; idp.polls == 1 {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"error":"authorization_pending"}`)
+				return
+			}
+
+			fmt.Fprint(w, `{"access_token":"device-token","refresh_token":"r1","expires_in":3600}`)
+
+		case "authorization_code":
+			verifier := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+			if r.Form.Get("code") != "c0d3" || base64.RawURLEncoding.EncodeToString(verifier[:]) != idp.challenge {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"error":"invalid_grant"}`)
+				return
+			}
+
+			fmt.Fprint(w, `{"access_token":"code-token","expires_in":3600}`)
+
+		case "refresh_token":
+			idp.refreshes++
+			fmt.Fprint(w, `{"access_token":"refreshed-token","expires_in":3600}`)
+		}
+	})
+
+	idp.Server = httptest.NewServer(mux)
+	return idp
+}
+
+func TestDiscoverIssuer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"rdapConformance":["rdap_level_0","farv1"],"farv1_openidcProviders":[{"iss":"https://other.example","name":"Other"},{"iss":"https://idp.example","name":"Example","default":true}]}`)
+	}))
+	defer server.Close()
+
+	issuer, err := discoverIssuer(http.DefaultClient, server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if issuer != "https://idp.example" {
+		t.Errorf("expected the default provider and got %s", issuer)
+	}
+}
+
+func TestLoginFlows(t *testing.T) {
+	devicePollUnit = time.Millisecond
+	defer func() { devicePollUnit = time.Second }()
+
+	idp := newIdentityProvider()
+	defer idp.Close()
+
+	provider, err := discoverProvider(http.DefaultClient, idp.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := &oidcClient{httpClient: http.DefaultClient, clientID: "rdap-client", scope: "openid"}
+
+	token, err := client.deviceFlow(provider, io.Discard)
+	if err != nil {
+		t.Fatalf("device flow: unexpected error %v", err)
+	}
+
+	if token.AccessToken != "device-token" || idp.polls != 2 {
+		t.Errorf("device flow: unexpected token %q after %d polls", token.AccessToken, idp.polls)
+	}
+
+	// the browser of the user is played by the prompt
+	token, err = client.codeFlow(provider, func(authURL string) {
+		go func() {
+			if resp, err := http.Get(authURL); err == nil {
+				resp.Body.Close()
+			}
+		}()
+	})
+	if err != nil {
+		t.Fatalf("code flow: unexpected error %v", err)
+	}
+
+	if token.AccessToken != "code-token" {
+		t.Errorf("code flow: unexpected token %q", token.AccessToken)
+	}
+
+	client.clientID = "unknown"
+	if _, err := client.deviceFlow(provider, io.Discard); err == nil {
+		t.Error("expected an error for an unknown client")
+	}
+}
+
+func TestTokenStore(t *testing.T) {
+	idp := newIdentityProvider()
+	defer idp.Close()
+
+	cache := t.TempDir()
+	tokens := newTokenStore(cache, http.DefaultClient)
+
+	creds, err := tokens.lookup("rdap.registro.br")
+	if creds != nil || err != nil {
+		t.Fatalf("expected no credentials without login, got %v and %v", creds, err)
+	}
+
+	idToken := "e30." + base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"joe"}`)) + ".c2ln"
+
+	expired := &storedToken{
+		Server:        "https://rdap.registro.br",
+		Issuer:        idp.URL,
+		TokenEndpoint: idp.URL + "/token",
+		ClientID:      "rdap-client",
+		AccessToken:   "old-token",
+		RefreshToken:  "r1",
+		IDToken:       idToken,
+		Expiry:        time.Now().Add(-time.Minute),
+	}
+
+	if err := tokens.save("rdap.example", expired); err != nil {
+		t.Fatal(err)
+	}
+
+	// the offline mode doesn't refresh the expired tokens
+	offline := newTokenStore(cache, http.DefaultClient)
+	offline.offline = true
+
+	offlineCreds, err := offline.lookup("rdap.example")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if offlineCreds == nil || offlineCreds.token != "old-token" || idp.refreshes != 0 {
+		t.Fatalf("expected the expired token without refresh and got %v after %d refreshes", offlineCreds, idp.refreshes)
+	}
+
+	// a new store reads the tokens saved by the login
+	tokens = newTokenStore(cache, http.DefaultClient)
+
+	for i := 0; i < 2; i++ {
+		creds, err = tokens.lookup("rdap.example")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if creds == nil || creds.token != "refreshed-token" {
+			t.Fatalf("expected the refreshed token and got %v", creds)
+		}
+	}
+
+	if idp.refreshes != 1 {
+		t.Errorf("expected 1 refresh and got %d", idp.refreshes)
+	}
+
+	// the refresh keeps the cached responses of the login
+	if before, after := offlineCreds.identity("rdap.example"), creds.identity("rdap.example"); before != after {
+		t.Errorf("expected the identity %s to be kept after the refresh and got %s", before, after)
+	}
+
+	content, err := os.ReadFile(tokens.path("rdap.example"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stored storedToken
+	if err := json.Unmarshal(content, &stored); err != nil {
+		t.Fatal(err)
+	}
+
+	if stored.AccessToken != "refreshed-token" || stored.RefreshToken != "r1" {
+		t.Errorf("unexpected stored tokens %+v", stored)
+	}
+}
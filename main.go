@@ -2,29 +2,24 @@ package main
 
 import (
 	"crypto/tls"
-	"encoding/json"
 	"fmt"
 	"net"
 	"net/http"
 	"net/url"
 	"os"
 	"path"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gregjones/httpcache"
 	"github.com/gregjones/httpcache/diskcache"
 	"github.com/registrobr/rdap"
 	"github.com/registrobr/rdap-client/output"
-	"github.com/registrobr/rdap/protocol"
 	"github.com/urfave/cli"
 )
 
-const (
-	outputTypeDefault = "default"
-	outputTypeRaw     = "raw"
-)
-
 func main() {
 	cli.AppHelpTemplate = `
 NAME:
@@ -32,6 +27,12 @@ NAME:
 
 USAGE:
    {{.Name}} {{if .Flags}}[global options]{{end}} OBJECT
+   {{.Name}} {{if .Flags}}[global options]{{end}} -f FILE
+   {{.Name}} {{if .Flags}}[global options]{{end}} COMMAND [command options]
+
+COMMANDS:
+   {{range .Commands}}{{join .Names ", "}}{{ "\t" }}{{.Usage}}
+   {{end}}
 
 VERSION:
    {{.Version}}{{if len .Authors}}
@@ -44,6 +45,12 @@ GLOBAL OPTIONS:
    {{end}}
 `
 
+	// -v is the short name of --trace
+	cli.VersionFlag = cli.BoolFlag{
+		Name:  "version",
+		Usage: "print the version",
+	}
+
 	app := cli.NewApp()
 	app.Name = "rdap"
 	app.Usage = "RDAP client"
@@ -52,57 +59,252 @@ GLOBAL OPTIONS:
 
 	app.Flags = []cli.Flag{
 		cli.StringFlag{
-			Name:  "cache",
-			Value: path.Join(os.Getenv("HOME"), ".rdap"),
-			Usage: "directory for caching bootstrap and RDAP data",
+			Name:   "config",
+			EnvVar: "RDAP_CONFIG",
+			Value:  defaultConfigFile(),
+			Usage:  "configuration file with the default values of the global options and the settings of the hosts",
+		},
+		cli.StringFlag{
+			Name:   "profile",
+			EnvVar: "RDAP_PROFILE",
+			Usage:  "named profile of the configuration file",
 		},
 		cli.StringFlag{
-			Name:  "bootstrap",
-			Value: rdap.IANABootstrap,
-			Usage: "RDAP bootstrap service URL",
+			Name:   "cache",
+			EnvVar: "RDAP_CACHE",
+			Value:  path.Join(os.Getenv("HOME"), ".rdap"),
+			Usage:  "directory for caching bootstrap and RDAP data",
+		},
+		cli.StringFlag{
+			Name:   "bootstrap",
+			EnvVar: "RDAP_BOOTSTRAP",
+			Value:  rdap.IANABootstrap,
+			Usage:  "RDAP bootstrap service URL",
+		},
+		cli.StringSliceFlag{
+			Name:   "bootstrap-file",
+			EnvVar: "RDAP_BOOTSTRAP_FILE",
+			Value:  &cli.StringSlice{},
+			Usage:  "local bootstrap registry, as “[registry=]path” (e.g. “dns.json” or “asn=/tmp/mirror.json”)",
+		},
+		cli.StringSliceFlag{
+			Name:   "bootstrap-override",
+			EnvVar: "RDAP_BOOTSTRAP_OVERRIDE",
+			Value:  &cli.StringSlice{},
+			Usage:  "RDAP server of a bootstrap entry, taking precedence over the registries, as “entry=URL” (e.g. “.br=https://rdap.example.br/”)",
 		},
 		cli.BoolFlag{
-			Name:  "no-cache",
-			Usage: "don't cache boostrap responses",
+			Name:   "no-cache",
+			EnvVar: "RDAP_NO_CACHE",
+			Usage:  "don't cache bootstrap and RDAP responses",
+		},
+		cli.DurationFlag{
+			Name:   "max-age",
+			EnvVar: "RDAP_MAX_AGE",
+			Usage:  "accept cached responses up to this age, replacing the lifetime defined by the server",
+		},
+		cli.BoolFlag{
+			Name:   "refresh",
+			EnvVar: "RDAP_REFRESH",
+			Usage:  "revalidate the cached responses with the servers",
+		},
+		cli.BoolFlag{
+			Name:   "offline",
+			EnvVar: "RDAP_OFFLINE",
+			Usage:  "answer only from the cache, without accessing the network",
+		},
+		cli.DurationFlag{
+			Name:   "negative-ttl",
+			EnvVar: "RDAP_NEGATIVE_TTL",
+			Value:  time.Minute,
+			Usage:  "time to cache the not found responses without caching directives (0 disables it)",
+		},
+		cli.BoolFlag{
+			Name:   "skip-tls-verification,S",
+			EnvVar: "RDAP_SKIP_TLS_VERIFICATION",
+			Usage:  "skip TLS verification",
+		},
+		cli.StringFlag{
+			Name:   "ca-file",
+			EnvVar: "RDAP_CA_FILE",
+			Usage:  "PEM file with the certificates of the CAs trusted besides the system ones",
+		},
+		cli.StringFlag{
+			Name:   "client-cert",
+			EnvVar: "RDAP_CLIENT_CERT",
+			Usage:  "PEM file with the client certificate, for the servers that require mutual TLS",
+		},
+		cli.StringFlag{
+			Name:   "client-key",
+			EnvVar: "RDAP_CLIENT_KEY",
+			Usage:  "PEM file with the private key of the client certificate",
+		},
+		cli.StringSliceFlag{
+			Name:   "pin",
+			EnvVar: "RDAP_PIN",
+			Value:  &cli.StringSlice{},
+			Usage:  "public key of the certificates of each host matching a pattern, as “[pattern=]sha256//hash” of the SPKI (e.g. “rdap.registro.br=sha256//AbC...=”)",
+		},
+		cli.StringSliceFlag{
+			Name:   "proxy",
+			EnvVar: "RDAP_PROXY",
+			Value:  &cli.StringSlice{},
+			Usage:  "proxy of the requests to each host matching a pattern, as “[pattern=]URL” with the “http”, “https” or “socks5” scheme (default: the HTTP_PROXY and HTTPS_PROXY environment variables)",
+		},
+		cli.StringSliceFlag{
+			Name:   "no-proxy",
+			EnvVar: "RDAP_NO_PROXY",
+			Value:  &cli.StringSlice{},
+			Usage:  "pattern of the hosts reached without proxy (e.g. “*.internal.example”)",
+		},
+		cli.StringFlag{
+			Name:   "credential-helper",
+			EnvVar: "RDAP_CREDENTIAL_HELPER",
+			Usage:  "command that writes the credentials of the host given as its last argument, as “username=”, “password=” or “token=” lines",
 		},
 		cli.BoolFlag{
-			Name:  "skip-tls-verification,S",
-			Usage: "skip TLS verification",
+			Name:   "domain",
+			EnvVar: "RDAP_DOMAIN",
+			Usage:  "force query for a domain object",
 		},
 		cli.BoolFlag{
-			Name:  "domain",
-			Usage: "force query for a domain object",
+			Name:   "asn",
+			EnvVar: "RDAP_ASN",
+			Usage:  "force query for an ASN object",
 		},
 		cli.BoolFlag{
-			Name:  "asn",
-			Usage: "force query for an ASN object",
+			Name:   "ip",
+			EnvVar: "RDAP_IP",
+			Usage:  "force query for an IP or IPNetwork object",
 		},
 		cli.BoolFlag{
-			Name:  "ip",
-			Usage: "force query for an IP or IPNetwork object",
+			Name:   "entity",
+			EnvVar: "RDAP_ENTITY",
+			Usage:  "force query for an Entity object",
 		},
 		cli.BoolFlag{
-			Name:  "entity",
-			Usage: "force query for an Entity object",
+			Name:   "nameserver",
+			EnvVar: "RDAP_NAMESERVER",
+			Usage:  "force query for a Nameserver object",
+		},
+		cli.StringSliceFlag{
+			Name:   "host,H",
+			EnvVar: "RDAP_HOST",
+			Value:  &cli.StringSlice{},
+			Usage:  "host where to send the query (bypass bootstrap), repeat it for the hosts tried when the previous one fails",
 		},
 		cli.StringFlag{
-			Name:  "host,H",
-			Value: "",
-			Usage: "host where to send the query (bypass bootstrap)",
+			Name:   "output-type,o",
+			EnvVar: "RDAP_OUTPUT_TYPE",
+			Value:  output.FormatDefault,
+			Usage:  "defines the output format, possible values are “" + strings.Join(output.Formats(), "”, “") + "”",
+		},
+		cli.StringSliceFlag{
+			Name:   "extra,x",
+			EnvVar: "RDAP_EXTRA",
+			Value:  &cli.StringSlice{},
+			Usage:  "set some extra options using key=value format",
 		},
 		cli.StringFlag{
-			Name:  "output-type,o",
-			Value: "default",
-			Usage: "defines the output format, possible values are “" + outputTypeDefault + "” and “" + outputTypeRaw + "”",
+			Name:   "file,f",
+			EnvVar: "RDAP_FILE",
+			Value:  "",
+			Usage:  "query each object listed in a file, one per line (“-” reads from stdin)",
+		},
+		cli.IntFlag{
+			Name:   "workers,w",
+			EnvVar: "RDAP_WORKERS",
+			Value:  4,
+			Usage:  "number of concurrent queries when reading objects from a file",
 		},
 		cli.StringSliceFlag{
-			Name:  "extra,x",
-			Value: &cli.StringSlice{},
-			Usage: "set some extra options using key=value format",
+			Name:   "template",
+			EnvVar: "RDAP_TEMPLATE",
+			Value:  &cli.StringSlice{},
+			Usage:  "template file used by the default output for an object type, in the format “TYPE=FILE” (e.g. “domain=oneline.tmpl”), instead of the one in the templates directory of the cache",
+		},
+		cli.DurationFlag{
+			Name:   "timeout",
+			EnvVar: "RDAP_TIMEOUT",
+			Value:  time.Minute,
+			Usage:  "maximum time of each request, including the retries (0 disables it)",
+		},
+		cli.DurationFlag{
+			Name:   "connect-timeout",
+			EnvVar: "RDAP_CONNECT_TIMEOUT",
+			Value:  10 * time.Second,
+			Usage:  "maximum time to establish a connection",
+		},
+		cli.IntFlag{
+			Name:   "retries",
+			EnvVar: "RDAP_RETRIES",
+			Value:  3,
+			Usage:  "number of retries on network errors and transient failures (429, 502, 503 and 504)",
 		},
+		cli.DurationFlag{
+			Name:   "retry-wait",
+			EnvVar: "RDAP_RETRY_WAIT",
+			Value:  500 * time.Millisecond,
+			Usage:  "wait before the first retry, doubled on each retry",
+		},
+		cli.DurationFlag{
+			Name:   "retry-max-wait",
+			EnvVar: "RDAP_RETRY_MAX_WAIT",
+			Value:  30 * time.Second,
+			Usage:  "maximum wait between retries, giving up when the server asks for a longer one",
+		},
+		cli.StringSliceFlag{
+			Name:   "rate-limit",
+			EnvVar: "RDAP_RATE_LIMIT",
+			Value:  &cli.StringSlice{},
+			Usage:  "maximum rate of requests to each host matching a pattern, as “[pattern=]requests[/s|/m|/h]” (e.g. “*.registro.br=2/s”)",
+		},
+		cli.StringSliceFlag{
+			Name:   "max-concurrent",
+			EnvVar: "RDAP_MAX_CONCURRENT",
+			Value:  &cli.StringSlice{},
+			Usage:  "maximum concurrent requests to each host matching a pattern, as “[pattern=]requests” (e.g. “rdap.arin.net=2”)",
+		},
+		cli.BoolFlag{
+			Name:   "follow-referrals",
+			EnvVar: "RDAP_FOLLOW_REFERRALS",
+			Usage:  "also query the domain objects referred by the registry, like the one of the registrar",
+		},
+		cli.BoolFlag{
+			Name:   "no-notices",
+			EnvVar: "RDAP_NO_NOTICES",
+			Usage:  "don't show the notices, remarks and links in the default output",
+		},
+		cli.BoolFlag{
+			Name:   "trace,v",
+			EnvVar: "RDAP_TRACE",
+			Usage:  "write to the standard error how the queries are resolved: the bootstrap entries, the HTTP requests and responses, and the timing of each phase",
+		},
+	}
+
+	app.Commands = []cli.Command{
+		searchCommand,
+		cacheCommand,
+		loginCommand,
 	}
+	app.Metadata = make(map[string]any)
+	app.Before = func(ctx *cli.Context) error {
+		p, err := readProfile(ctx)
+		if err == nil {
+			err = applyProfile(ctx, p)
+		}
+
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		if ctx.Bool("trace") {
+			ctx.App.Metadata["tracer"] = &tracer{w: os.Stderr}
+		}
 
-	app.Commands = []cli.Command{}
+		return nil
+	}
 	app.Action = action
 
 	app.Run(os.Args)
@@ -110,20 +312,20 @@ GLOBAL OPTIONS:
 
 func action(ctx *cli.Context) {
 	var (
-		cache               = ctx.String("cache")
-		bootstrapURI        = ctx.String("bootstrap")
-		host                = ctx.String("host")
-		outputType          = ctx.String("output-type")
-		skipTLSVerification = ctx.Bool("skip-tls-verification")
-		forceASN            = ctx.Bool("asn")
-		forceDomain         = ctx.Bool("domain")
-		forceEntity         = ctx.Bool("entity")
-		forceIP             = ctx.Bool("ip")
-		extraOptions        = ctx.StringSlice("extra")
+		forceASN     = ctx.Bool("asn")
+		forceDomain  = ctx.Bool("domain")
+		forceEntity  = ctx.Bool("entity")
+		forceIP      = ctx.Bool("ip")
+		forceNS      = ctx.Bool("nameserver")
+		extraOptions = ctx.StringSlice("extra")
+		file         = ctx.String("file")
+		workers      = ctx.Int("workers")
+		referrals    = ctx.Bool("follow-referrals")
 	)
 
-	if outputType != outputTypeDefault && outputType != outputTypeRaw {
-		fmt.Fprintln(os.Stderr, "invalid output type")
+	outputType, printOptions, err := newPrintOptions(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
 
@@ -133,154 +335,431 @@ func action(ctx *cli.Context) {
 		forceIP,
 		forceEntity,
 		forceASN,
+		forceNS,
 	}
 
 	for _, force := range forceObjects {
 		if force {
 			if forceCount++; forceCount > 1 {
-				fmt.Fprintln(os.Stderr, "you can't use -asn, -domain, -entity or -ip at the same time")
+				fmt.Fprintln(os.Stderr, "you can't use -asn, -domain, -entity, -ip or -nameserver at the same time")
 				os.Exit(1)
 			}
 		}
 	}
 
-	bsHTTPClient := &http.Client{}
-	rdapHTTPClient := &http.Client{
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: skipTLSVerification,
-			},
-		},
+	bsHTTPClient, rdapHTTPClient, err := newHTTPClients(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
 
-	if !ctx.Bool("no-cache") {
-		transport := httpcache.NewTransport(
-			diskcache.New(cache),
-		)
+	var identifiers []string
 
-		transport.Transport = &http.Transport{
-			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: skipTLSVerification,
-			},
+	if len(file) > 0 {
+		if identifiers, err = readIdentifiers(file); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
 		}
 
-		bsHTTPClient.Transport = transport
-	}
+	} else if identifier := strings.Join(ctx.Args(), " "); identifier != "" {
+		identifiers = append(identifiers, identifier)
 
-	identifier := strings.Join(ctx.Args(), " ")
-	if identifier == "" {
+	} else {
 		cli.ShowAppHelp(ctx)
 		os.Exit(1)
 	}
 
+	client, err := newClient(ctx, bsHTTPClient, rdapHTTPClient)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	queryString, err := parseExtraOptions(extraOptions)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	lookup := func(identifier string) *output.Result {
+		var (
+			object any
+			err    error
+			start  = time.Now()
+			result = &output.Result{Query: identifier}
+		)
+
+		client := *client
+		if fetcher, ok := client.Transport.(resultFetcher); ok {
+			client.Transport = fetcher.withResult(result)
+		}
+
+		client.Transport = &recorder{
+			fetcher: client.Transport,
+			result:  result,
+		}
+
+		switch {
+		case forceASN:
+			var asn uint64
+			if asn, err = strconv.ParseUint(identifier, 10, 32); err == nil {
+				object, _, err = client.ASN(uint32(asn), nil, queryString)
+			}
+
+		case forceDomain:
+			object, _, err = client.Domain(identifier, nil, queryString)
+
+		case forceEntity:
+			object, _, err = client.Entity(identifier, nil, queryString)
+
+		case forceNS:
+			object, err = queryNameserver(&client, identifier, queryString)
+
+		case forceIP:
+			if ip := net.ParseIP(identifier); ip != nil {
+				object, _, err = client.IP(ip, nil, queryString)
+			} else {
+				var ipnetwork *net.IPNet
+
+				if _, ipnetwork, err = net.ParseCIDR(identifier); err != nil {
+					err = fmt.Errorf("invalid ip or ip network “%s”", identifier)
+				} else {
+					object, _, err = client.IPNetwork(ipnetwork, nil, queryString)
+				}
+			}
+
+		default:
+			object, _, err = client.Query(identifier, nil, queryString)
+		}
+
+		result.Elapsed = time.Since(start)
+		result.Object = object
+		result.Err = err
+
+		if referrals {
+			followReferrals(rdapHTTPClient, result, queryString)
+		}
+
+		return result
+	}
+
+	status := 0
+
+	lookupAll(identifiers, workers, lookup, func(r *output.Result) {
+		if err := printResult(r, outputType, printOptions); err != nil {
+			if len(file) > 0 {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", r.Query, err)
+			} else {
+				fmt.Fprintln(os.Stderr, err)
+			}
+
+			status = 1
+		}
+
+		if r.Err != nil {
+			status = 1
+		}
+	})
+
+	os.Exit(status)
+}
+
+// globalContext returns the context of the application, where the global
+// options are stored, from the context of any command
+func globalContext(ctx *cli.Context) *cli.Context {
+	for ctx.Parent() != nil {
+		ctx = ctx.Parent()
+	}
+
+	return ctx
+}
+
+// newPrintOptions returns the output format and the printer options chosen
+// with the global options
+func newPrintOptions(ctx *cli.Context) (string, output.Options, error) {
+	var (
+		cache         = ctx.String("cache")
+		outputType    = ctx.String("output-type")
+		templateFiles = ctx.StringSlice("template")
+	)
+
+	if !slices.Contains(output.Formats(), outputType) {
+		return "", output.Options{}, fmt.Errorf("invalid output type")
+	}
+
+	templates, err := loadTemplates(path.Join(cache, "templates"), templateFiles)
+	if err != nil {
+		return "", output.Options{}, err
+	}
+
+	return outputType, output.Options{
+		Templates:   templates,
+		HideNotices: ctx.Bool("no-notices"),
+	}, nil
+}
+
+// newHTTPClients builds the HTTP clients used to retrieve the bootstrap files
+// and to query the RDAP servers directly. Both retry the transient failures,
+// share the limits of each host and cache the responses. The bootstrap
+// registries also come from the local files and overrides
+func newHTTPClients(ctx *cli.Context) (bsHTTPClient, rdapHTTPClient *http.Client, err error) {
+	var (
+		cache   = ctx.String("cache")
+		timeout = ctx.Duration("timeout")
+		offline = ctx.Bool("offline")
+	)
+
+	if offline && ctx.Bool("no-cache") {
+		return nil, nil, fmt.Errorf("the offline mode needs the cache")
+	} else if offline && ctx.Bool("refresh") {
+		return nil, nil, fmt.Errorf("the offline mode can't refresh the cache")
+	}
+
+	rates, err := parseHostRules(ctx.StringSlice("rate-limit"), true)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	concurrency, err := parseHostRules(ctx.StringSlice("max-concurrent"), false)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	limiter := newRateLimiter(rates, concurrency)
+
+	tlsConfig, err := newTLSConfig(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	proxy, err := newProxy(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	store := newCredentialStore(configuredHosts(ctx), ctx.String("credential-helper"))
+	store.tokens = newTokenStore(cache, newDirectHTTPClient(ctx, limiter, tlsConfig, proxy))
+	store.tokens.offline = offline
+
+	bsHTTPClient = &http.Client{
+		Transport: newTransport(ctx, limiter, tlsConfig, proxy, store),
+		Timeout:   timeout,
+	}
+	rdapHTTPClient = &http.Client{
+		Transport: newTransport(ctx, limiter, tlsConfig, proxy, store),
+		Timeout:   timeout,
+	}
+
+	files, err := parseBootstrapFiles(ctx.StringSlice("bootstrap-file"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	overrides, err := parseBootstrapOverrides(ctx.StringSlice("bootstrap-override"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !ctx.Bool("no-cache") {
+		diskCache := diskcache.New(cache)
+
+		for _, httpClient := range []*http.Client{bsHTTPClient, rdapHTTPClient} {
+			if offline {
+				httpClient.Transport = offlineTransport{}
+			}
+
+			transport := httpcache.NewTransport(diskCache)
+			transport.Transport = &cacheStamp{
+				transport:   httpClient.Transport,
+				negativeTTL: ctx.Duration("negative-ttl"),
+			}
+
+			httpClient.Transport = &cachePolicy{
+				transport: transport,
+				store:     store,
+				maxAge:    ctx.Duration("max-age"),
+				refresh:   ctx.Bool("refresh"),
+				offline:   offline,
+			}
+		}
+	}
+
+	if len(files) > 0 || len(overrides) > 0 {
+		bsHTTPClient.Transport = &bootstrapTransport{
+			transport:    bsHTTPClient.Transport,
+			bootstrapURI: ctx.String("bootstrap"),
+			files:        files,
+			overrides:    overrides,
+		}
+	}
+
+	if tracer := traceOf(ctx); tracer != nil {
+		bsHTTPClient.Transport = &traceTransport{transport: bsHTTPClient.Transport, tracer: tracer}
+		rdapHTTPClient.Transport = &traceTransport{transport: rdapHTTPClient.Transport, tracer: tracer}
+	}
+
+	return bsHTTPClient, rdapHTTPClient, nil
+}
+
+// newTransport builds the transport of the HTTP clients, with the
+// connection settings, the proxy and the retries chosen with the global
+// options. Each attempt waits for the limits of the host, and carries the
+// headers and the credentials of the host
+func newTransport(ctx *cli.Context, limiter *rateLimiter, tlsConfig *tls.Config, proxy func(*http.Request) (*url.URL, error), store *credentialStore) http.RoundTripper {
+	connectTimeout := ctx.Duration("connect-timeout")
+
+	dialer := &net.Dialer{
+		Timeout:   connectTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+
+	transport := &hostTransport{
+		transport: &http.Transport{
+			Proxy:               proxy,
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: connectTimeout,
+			TLSClientConfig:     tlsConfig.Clone(),
+		},
+		store: store,
+	}
+
+	return &retryTransport{
+		transport: &rateLimitTransport{
+			transport: transport,
+			limiter:   limiter,
+		},
+		retries: ctx.Int("retries"),
+		wait:    ctx.Duration("retry-wait"),
+		maxWait: ctx.Duration("retry-max-wait"),
+	}
+}
+
+// newDirectHTTPClient returns an HTTP client without the cache and the
+// credentials of the logins, for the requests to the OpenID providers
+func newDirectHTTPClient(ctx *cli.Context, limiter *rateLimiter, tlsConfig *tls.Config, proxy func(*http.Request) (*url.URL, error)) *http.Client {
+	transport := newTransport(ctx, limiter, tlsConfig, proxy, newCredentialStore(configuredHosts(ctx), ""))
+	if tracer := traceOf(ctx); tracer != nil {
+		transport = &traceTransport{transport: transport, tracer: tracer}
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   ctx.Duration("timeout"),
+	}
+}
+
+// newClient returns an RDAP client that queries the hosts given in the global
+// options or, when there's none, uses the bootstrap strategy. The servers
+// are tried in order until one of them answers
+func newClient(ctx *cli.Context, bsHTTPClient, rdapHTTPClient *http.Client) (*rdap.Client, error) {
+	var (
+		bootstrapURI = ctx.String("bootstrap")
+		hosts        = ctx.StringSlice("host")
+	)
+
 	var client rdap.Client
+	fetcher := &failoverFetcher{httpClient: rdapHTTPClient}
 
-	if len(host) > 0 {
-		u, err := url.Parse(host)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "%s\n", err)
-			os.Exit(1)
+	if len(hosts) > 0 {
+		for _, host := range hosts {
+			u, err := url.Parse(host)
+			if err != nil {
+				return nil, err
+			}
+
+			client.URIs = append(client.URIs, u.String())
 		}
 
-		client.URIs = append(client.URIs, u.String())
-		client.Transport = rdap.NewDefaultFetcher(rdapHTTPClient)
+		client.Transport = fetcher
 
 	} else {
+		tracer := traceOf(ctx)
+
+		// in offline mode the bootstrap files are never reloaded, as that
+		// would also look up the nameservers of the domain
 		cacheDetector := rdap.CacheDetector(func(resp *http.Response) bool {
-			return resp.Header.Get(httpcache.XFromCache) == "1"
+			cached := fromCache(resp)
+			if tracer != nil && resp.Request != nil {
+				if cached {
+					tracer.printf("bootstrap registry %s from cache, retrieved at %s",
+						resp.Request.URL, fetchedAt(resp).UTC().Format(time.RFC3339))
+				} else {
+					tracer.printf("bootstrap registry %s not from cache", resp.Request.URL)
+				}
+			}
+
+			return !ctx.Bool("offline") && cached
 		})
 
-		client.Transport = rdap.NewBootstrapFetcher(bsHTTPClient, bootstrapURI, cacheDetector)
+		client.Transport = &registryFetcher{
+			fetcher:       fetcher,
+			httpClient:    bsHTTPClient,
+			bootstrapURI:  bootstrapURI,
+			cacheDetector: cacheDetector,
+			tracer:        tracer,
+		}
 	}
 
+	return &client, nil
+}
+
+func parseExtraOptions(extraOptions []string) (url.Values, error) {
 	queryString := make(url.Values)
 
 	for _, extraOption := range extraOptions {
 		extraOptionParts := strings.Split(extraOption, "=")
 		if len(extraOptionParts) != 2 {
-			fmt.Fprintln(os.Stderr, "invalid extra option “"+extraOption+"”")
-			os.Exit(1)
+			return nil, fmt.Errorf("invalid extra option “%s”", extraOption)
 		}
 
 		key, value := strings.TrimSpace(extraOptionParts[0]), strings.TrimSpace(extraOptionParts[1])
 		queryString.Add(key, value)
 	}
 
-	var err error
-	var object any
+	return queryString, nil
+}
 
-	switch {
-	case forceASN:
-		var asn uint64
-		if asn, err = strconv.ParseUint(identifier, 10, 32); err == nil {
-			object, _, err = client.ASN(uint32(asn), nil, queryString)
+// printResult writes the query result to the standard output. When the
+// output format can't describe a failed query, the query error is returned
+// to be reported. The default output tells how the query was resolved, and
+// the objects of the referrals follow the object in the default and raw
+// outputs
+func printResult(r *output.Result, outputType string, opts output.Options) error {
+	printer, err := output.NewPrinter(outputType, r, opts)
+	if err != nil {
+		if r.Err != nil {
+			return r.Err
 		}
 
-	case forceDomain:
-		object, _, err = client.Domain(identifier, nil, queryString)
-
-	case forceEntity:
-		object, _, err = client.Entity(identifier, nil, queryString)
-
-	case forceIP:
-		if ip := net.ParseIP(identifier); ip != nil {
-			object, _, err = client.IP(ip, nil, queryString)
-		} else {
-			var ipnetwork *net.IPNet
-
-			if _, ipnetwork, err = net.ParseCIDR(identifier); err != nil {
-				err = fmt.Errorf("invalid ip or ip network “%s”", identifier)
-			} else {
-				object, _, err = client.IPNetwork(ipnetwork, nil, queryString)
-			}
-		}
+		return err
+	}
 
-	default:
-		object, _, err = client.Query(identifier, nil, queryString)
+	if outputType == output.FormatDefault {
+		printer = &output.QueryNotice{Printer: printer, Result: r}
 	}
 
-	if err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+	if err := printer.Print(os.Stdout); err != nil {
+		return err
 	}
 
-	switch outputType {
-	case outputTypeDefault:
-		var printer output.Printer
+	// the other formats nest or merge the referrals in the result
+	if outputType != output.FormatDefault && outputType != output.FormatRaw {
+		return nil
+	}
 
-		switch object.(type) {
-		case *protocol.AS:
-			printer = &output.AS{
-				AS: object.(*protocol.AS),
-			}
-		case *protocol.Domain:
-			printer = &output.Domain{
-				Domain: object.(*protocol.Domain),
-			}
-		case *protocol.Entity:
-			printer = &output.Entity{
-				Entity: object.(*protocol.Entity),
-			}
-		case *protocol.IPNetwork:
-			printer = &output.IPNetwork{
-				IPNetwork: object.(*protocol.IPNetwork),
+	for _, referral := range r.Referrals {
+		if referral.Err != nil {
+			if outputType == output.FormatDefault {
+				fmt.Printf("%% referral from %s failed: %s\n", referral.ReferredBy, referral.Err)
 			}
-		}
 
-		if err := printer.Print(os.Stdout); err != nil {
-			fmt.Fprintln(os.Stderr, err)
-			os.Exit(1)
+			continue
 		}
 
-	case outputTypeRaw:
-		output, err := json.MarshalIndent(object, "", "  ")
-		if err != nil {
-			fmt.Fprintln(os.Stderr, err)
-			os.Exit(1)
+		if err := printResult(referral, outputType, opts); err != nil {
+			return err
 		}
-
-		fmt.Println(string(output))
 	}
 
-	os.Exit(0)
+	return nil
 }
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+
+	"github.com/registrobr/rdap"
+	"github.com/registrobr/rdap/protocol"
+	"golang.org/x/net/idna"
+)
+
+// queryTypeNameserver is used to retrieve the nameserver objects, as defined
+// in RFC 9082, section 3.1.4
+const queryTypeNameserver rdap.QueryType = "nameserver"
+
+// queryNameserver retrieves a nameserver by its name, as the RDAP client
+// doesn't support this object type
+func queryNameserver(client *rdap.Client, name string, queryString url.Values) (*protocol.Nameserver, error) {
+	name, err := idna.ToASCII(strings.ToLower(name))
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Transport.Fetch(client.URIs, queryTypeNameserver, name, nil, queryString)
+	defer func() {
+		if resp != nil && resp.Body != nil {
+			resp.Body.Close()
+		}
+	}()
+
+	if err != nil {
+		return nil, err
+	}
+
+	nameserver := &protocol.Nameserver{}
+	if err = json.NewDecoder(resp.Body).Decode(nameserver); err != nil {
+		return nil, err
+	}
+
+	return nameserver, nil
+}
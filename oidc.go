@@ -0,0 +1,372 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// devicePollUnit is the unit of the polling interval of the device
+// authorization flow, that the identity provider gives in seconds
+var devicePollUnit = time.Second
+
+// codeFlowTimeout is how long the authorization code flow waits for the user
+// to log in
+const codeFlowTimeout = 5 * time.Minute
+
+// rdapHelp is the part of the help response of an RDAP server that lists the
+// OpenID providers accepted by the server, as defined in RFC 9560, section
+// 4.1
+type rdapHelp struct {
+	Providers []struct {
+		Issuer  string `json:"iss"`
+		Name    string `json:"name"`
+		Default bool   `json:"default"`
+	} `json:"farv1_openidcProviders"`
+}
+
+// discoverIssuer returns the issuer identifier of the default OpenID
+// provider advertised by the RDAP server, or of the only one
+func discoverIssuer(httpClient *http.Client, server string) (string, error) {
+	req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(server, "/")+"/help", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/rdap+json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status code %d %s from the help of %s", resp.StatusCode, http.StatusText(resp.StatusCode), server)
+	}
+
+	var help rdapHelp
+	if err := json.NewDecoder(resp.Body).Decode(&help); err != nil {
+		return "", fmt.Errorf("invalid help response from %s: %w", server, err)
+	}
+
+	for _, provider := range help.Providers {
+		if provider.Default || len(help.Providers) == 1 {
+			return provider.Issuer, nil
+		}
+	}
+
+	if len(help.Providers) == 0 {
+		return "", fmt.Errorf("%s doesn't advertise OpenID providers, choose one with --issuer", server)
+	}
+
+	var issuers []string
+	for _, provider := range help.Providers {
+		issuers = append(issuers, provider.Issuer)
+	}
+
+	return "", fmt.Errorf("%s advertises more than one OpenID provider, choose one with --issuer: %s", server, strings.Join(issuers, ", "))
+}
+
+// oidcProvider is the metadata of an OpenID provider, from its discovery
+// document
+type oidcProvider struct {
+	Issuer                      string `json:"issuer"`
+	AuthorizationEndpoint       string `json:"authorization_endpoint"`
+	TokenEndpoint               string `json:"token_endpoint"`
+	DeviceAuthorizationEndpoint string `json:"device_authorization_endpoint"`
+}
+
+// discoverProvider reads the discovery document of the OpenID provider
+func discoverProvider(httpClient *http.Client, issuer string) (*oidcProvider, error) {
+	uri := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+
+	var provider oidcProvider
+	if err := fetchBootstrapRegistry(httpClient, uri, &provider); err != nil {
+		return nil, fmt.Errorf("OpenID discovery of %s failed: %w", issuer, err)
+	}
+
+	if strings.TrimSuffix(provider.Issuer, "/") != strings.TrimSuffix(issuer, "/") {
+		return nil, fmt.Errorf("OpenID discovery of %s returned the issuer %s", issuer, provider.Issuer)
+	}
+
+	if provider.TokenEndpoint == "" {
+		return nil, fmt.Errorf("OpenID provider %s has no token endpoint", issuer)
+	}
+
+	return &provider, nil
+}
+
+// oidcClient is the registration of the client in the OpenID provider
+type oidcClient struct {
+	httpClient   *http.Client
+	clientID     string
+	clientSecret string
+	scope        string
+}
+
+// tokenResponse is the response of the token endpoint, as defined in RFC
+// 6749, sections 5.1 and 5.2
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	RefreshToken     string `json:"refresh_token"`
+	IDToken          string `json:"id_token"`
+	ExpiresIn        int64  `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// oauthError is an error returned by an endpoint of the identity provider
+type oauthError struct {
+	Code        string
+	Description string
+}
+
+func (e *oauthError) Error() string {
+	if e.Description != "" {
+		return fmt.Sprintf("%s: %s", e.Code, e.Description)
+	}
+
+	return e.Code
+}
+
+// post sends the form to an endpoint of the identity provider, with the
+// credentials of the client, decoding the JSON response
+func (c *oidcClient) post(endpoint string, form url.Values, response any) error {
+	form.Set("client_id", c.clientID)
+	if c.clientSecret != "" {
+		form.Set("client_secret", c.clientSecret)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	var oauthErr struct {
+		Error       string `json:"error"`
+		Description string `json:"error_description"`
+	}
+
+	if json.Unmarshal(body, &oauthErr) == nil && oauthErr.Error != "" {
+		return &oauthError{Code: oauthErr.Error, Description: oauthErr.Description}
+	} else if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d %s from %s", resp.StatusCode, http.StatusText(resp.StatusCode), endpoint)
+	}
+
+	return json.Unmarshal(body, response)
+}
+
+// requestToken sends a token request, checking that the response has an
+// access token
+func (c *oidcClient) requestToken(endpoint string, form url.Values) (*tokenResponse, error) {
+	var token tokenResponse
+	if err := c.post(endpoint, form, &token); err != nil {
+		return nil, err
+	}
+
+	if token.AccessToken == "" {
+		return nil, fmt.Errorf("no access token in the response of %s", endpoint)
+	}
+
+	return &token, nil
+}
+
+// refresh exchanges a refresh token for new tokens
+func (c *oidcClient) refresh(tokenEndpoint, refreshToken string) (*tokenResponse, error) {
+	return c.requestToken(tokenEndpoint, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refreshToken},
+	})
+}
+
+// deviceFlow runs the device authorization grant of RFC 8628, telling the
+// user where to enter the code and polling the token endpoint until the user
+// logs in
+func (c *oidcClient) deviceFlow(provider *oidcProvider, prompt io.Writer) (*tokenResponse, error) {
+	if provider.DeviceAuthorizationEndpoint == "" {
+		return nil, fmt.Errorf("OpenID provider %s doesn't support the device flow", provider.Issuer)
+	}
+
+	var authorization struct {
+		DeviceCode              string `json:"device_code"`
+		UserCode                string `json:"user_code"`
+		VerificationURI         string `json:"verification_uri"`
+		VerificationURIComplete string `json:"verification_uri_complete"`
+		ExpiresIn               int64  `json:"expires_in"`
+		Interval                int64  `json:"interval"`
+	}
+
+	err := c.post(provider.DeviceAuthorizationEndpoint, url.Values{"scope": {c.scope}}, &authorization)
+	if err != nil {
+		return nil, fmt.Errorf("device authorization failed: %w", err)
+	}
+
+	if authorization.VerificationURIComplete != "" {
+		fmt.Fprintf(prompt, "Open %s to log in, and check that it shows the code %s\n",
+			authorization.VerificationURIComplete, authorization.UserCode)
+	} else {
+		fmt.Fprintf(prompt, "Open %s to log in, and enter the code %s\n",
+			authorization.VerificationURI, authorization.UserCode)
+	}
+
+	interval := time.Duration(authorization.Interval) * devicePollUnit
+	if interval <= 0 {
+		interval = 5 * devicePollUnit
+	}
+
+	deadline := time.Now().Add(time.Duration(authorization.ExpiresIn) * time.Second)
+
+	for authorization.ExpiresIn <= 0 || time.Now().Before(deadline) {
+		time.Sleep(interval)
+
+		token, err := c.requestToken(provider.TokenEndpoint, url.Values{
+			"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+			"device_code": {authorization.DeviceCode},
+		})
+
+		var oauthErr *oauthError
+		if errors.As(err, &oauthErr) && oauthErr.Code == "authorization_pending" {
+			continue
+		} else if errors.As(err, &oauthErr) && oauthErr.Code == "slow_down" {
+			interval += 5 * devicePollUnit
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("login failed: %w", err)
+		}
+
+		return token, nil
+	}
+
+	return nil, errors.New("login failed: the device code expired")
+}
+
+// codeFlow runs the authorization code grant with PKCE (RFC 7636), receiving
+// the code in a local HTTP server as described in RFC 8252, section 7.3. The
+// prompt is called with the URL the user opens to log in
+func (c *oidcClient) codeFlow(provider *oidcProvider, prompt func(string)) (*tokenResponse, error) {
+	if provider.AuthorizationEndpoint == "" {
+		return nil, fmt.Errorf("OpenID provider %s doesn't support the authorization code flow", provider.Issuer)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	defer listener.Close()
+
+	redirectURI := fmt.Sprintf("http://%s/callback", listener.Addr())
+	state, verifier := randomString(), randomString()
+	challenge := sha256.Sum256([]byte(verifier))
+
+	authURL, err := url.Parse(provider.AuthorizationEndpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	query := authURL.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", c.clientID)
+	query.Set("redirect_uri", redirectURI)
+	query.Set("scope", c.scope)
+	query.Set("state", state)
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	query.Set("code_challenge_method", "S256")
+	authURL.RawQuery = query.Encode()
+
+	type callback struct {
+		code string
+		err  error
+	}
+	callbacks := make(chan callback, 1)
+
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/callback" {
+				http.NotFound(w, r)
+				return
+			}
+
+			var result callback
+			switch query := r.URL.Query(); {
+			case query.Get("state") != state:
+				result.err = errors.New("invalid state in the authorization response")
+			case query.Get("error") != "":
+				result.err = &oauthError{Code: query.Get("error"), Description: query.Get("error_description")}
+			default:
+				result.code = query.Get("code")
+			}
+
+			if result.err != nil {
+				http.Error(w, "Login failed: "+result.err.Error(), http.StatusBadRequest)
+			} else {
+				fmt.Fprintln(w, "Logged in, you can close this window.")
+			}
+
+			select {
+			case callbacks <- result:
+			default:
+			}
+		}),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go server.Serve(listener)
+	defer server.Close()
+
+	prompt(authURL.String())
+
+	var result callback
+	select {
+	case result = <-callbacks:
+	case <-time.After(codeFlowTimeout):
+		return nil, errors.New("login failed: timed out waiting for the authorization")
+	}
+
+	if result.err != nil {
+		return nil, fmt.Errorf("login failed: %w", result.err)
+	}
+
+	token, err := c.requestToken(provider.TokenEndpoint, url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {result.code},
+		"redirect_uri":  {redirectURI},
+		"code_verifier": {verifier},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("login failed: %w", err)
+	}
+
+	return token, nil
+}
+
+func randomString() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"io"
 	"strings"
-	"text/template"
 
 	"github.com/registrobr/rdap/protocol"
 )
@@ -15,6 +14,18 @@ type AS struct {
 	UpdatedAt     protocol.EventDate
 	IPNetworks    []string
 	ContactsInfos []contactInfo
+
+	// Template replaces the default template when defined
+	Template string
+
+	// HideNotices leaves the notices, remarks and links out of the output
+	HideNotices bool
+	Notices     *noticeSection
+
+	// Redacted lists the members of the response removed or changed by the
+	// server, as described in RFC 9537
+	Redacted   []Redaction
+	Redactions []RedactedField
 }
 
 func (a *AS) addContact(c contactInfo) {
@@ -54,16 +65,27 @@ func (a *AS) setIPNetworks() {
 	}
 }
 
-func (a *AS) Print(wr io.Writer) error {
+func (a *AS) prepare() {
 	a.setDates()
 	a.setIPNetworks()
 	addContacts(a, a.AS.Entities)
 	filterContacts(a)
+	a.Redactions = applyRedactions(a.ContactsInfos, a.Redacted, false)
+
+	if !a.HideNotices {
+		a.Notices = newNoticeSection(a.AS.Notices, a.AS.Remarks, a.AS.Links)
+	}
+}
 
-	t, err := template.New("as template").
-		Funcs(genericFuncMap).
-		Parse(strings.ReplaceAll(asTmpl, "\\\n", ""))
+func (a *AS) Print(wr io.Writer) error {
+	a.prepare()
+
+	tmpl := asTmpl
+	if a.Template != "" {
+		tmpl = a.Template
+	}
 
+	t, err := newTemplate("as template", tmpl)
 	if err != nil {
 		return err
 	}
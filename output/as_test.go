@@ -130,6 +130,8 @@ phone:    tel:+55-11-5509-3506;ext=3507
 created:  20150301
 changed:  20150310
 
+link:     https://rdap.registro.br/ip/200.160.0.0/20 (related)
+
 `
 
 	var w WriterMock
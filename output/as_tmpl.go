@@ -29,4 +29,4 @@ as-out:      to AS{{.Traffic}} announce {{.Autnum}}
 {{end}}\
 {{end}}\
 
-` + contactTmpl
+` + contactTmpl + redactionsTmplCall + noticesTmplCall
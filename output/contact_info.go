@@ -17,6 +17,9 @@ type contactInfo struct {
 	Roles     []string
 	CreatedAt protocol.EventDate
 	UpdatedAt protocol.EventDate
+
+	// Redactions lists the fields of the contact hidden by the server
+	Redactions []RedactedField
 }
 
 func (c *contactInfo) setContact(entity protocol.Entity) {
@@ -132,11 +135,16 @@ func addContacts(c contactList, entities []protocol.Entity) {
 func filterContacts(c contactList) {
 	contacts := make(map[string]*contactInfo)
 
+	// handles keeps the order in which the contacts first appeared, so the
+	// output doesn't depend on the map iteration order
+	var handles []string
+
 	for _, contactInfo := range c.getContacts() {
 		contactInfo := contactInfo
 
 		if _, ok := contacts[contactInfo.Handle]; !ok {
 			contacts[contactInfo.Handle] = &contactInfo
+			handles = append(handles, contactInfo.Handle)
 			continue
 		}
 
@@ -162,8 +170,8 @@ func filterContacts(c contactList) {
 
 	filteredContacts := make([]contactInfo, 0)
 
-	for _, contact := range contacts {
-		filteredContacts = append(filteredContacts, *contact)
+	for _, handle := range handles {
+		filteredContacts = append(filteredContacts, *contacts[handle])
 	}
 
 	c.setContacts(filteredContacts)
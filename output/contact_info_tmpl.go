@@ -20,6 +20,9 @@ address:  {{.}}
 {{range .Phones}}\
 phone:    {{.}}
 {{end}}\
+{{range .Redactions}}\
+{{printf "%-10s" (print .Label ":")}}{{template "redacted" .}}
+{{end}}\
 {{if (isDateDefined .CreatedAt)}}\
 created:  {{.CreatedAt | formatDate}}
 {{end}}\
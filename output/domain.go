@@ -2,8 +2,6 @@ package output
 
 import (
 	"io"
-	"strings"
-	"text/template"
 
 	"github.com/registrobr/rdap/protocol"
 )
@@ -18,6 +16,18 @@ type Domain struct {
 	Handles       map[string]string
 	DS            []ds
 	ContactsInfos []contactInfo
+
+	// Template replaces the default template when defined
+	Template string
+
+	// HideNotices leaves the notices, remarks and links out of the output
+	HideNotices bool
+	Notices     *noticeSection
+
+	// Redacted lists the members of the response removed or changed by the
+	// server, as described in RFC 9537
+	Redacted   []Redaction
+	Redactions []RedactedField
 }
 
 type ds struct {
@@ -70,17 +80,27 @@ func (d *Domain) setDS() {
 	}
 }
 
-func (d *Domain) Print(wr io.Writer) error {
+func (d *Domain) prepare() {
 	d.setDates()
 	d.setDS()
 	addContacts(d, d.Domain.Entities)
 	filterContacts(d)
+	d.Redactions = applyRedactions(d.ContactsInfos, d.Redacted, false)
+
+	if !d.HideNotices {
+		d.Notices = newNoticeSection(d.Domain.Notices, d.Domain.Remarks, d.Domain.Links)
+	}
+}
 
-	t, err := template.New("domain template").
-		Funcs(genericFuncMap).
-		Funcs(domainFuncMap).
-		Parse(strings.ReplaceAll(domainTmpl, "\\\n", ""))
+func (d *Domain) Print(wr io.Writer) error {
+	d.prepare()
+
+	tmpl := domainTmpl
+	if d.Template != "" {
+		tmpl = d.Template
+	}
 
+	t, err := newTemplate("domain template", tmpl, domainFuncMap)
 	if err != nil {
 		return err
 	}
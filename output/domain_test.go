@@ -170,3 +170,49 @@ changed:  20150310
 		t.Fatal("error")
 	}
 }
+
+func TestDomainPrintWithTemplate(t *testing.T) {
+	domain := Domain{
+		Domain: &protocol.Domain{
+			ObjectClassName: "domain",
+			LDHName:         "example.br",
+			Entities: []protocol.Entity{
+				{
+					ObjectClassName: "entity",
+					Handle:          "XXXX",
+					Roles:           []string{"registrant"},
+				},
+			},
+			Events: []protocol.Event{
+				{
+					Action: protocol.EventActionExpiration,
+					Date:   protocol.Date(2016, 03, 01, 12, 00, 00, 00, time.UTC),
+				},
+			},
+			Nameservers: []protocol.Nameserver{
+				{ObjectClassName: "nameserver", LDHName: "a.dns.br"},
+			},
+		},
+		Template: `{{.Domain.LDHName}} {{.ExpiresAt | formatDate}}\
+{{range .Domain.Nameservers}} {{.LDHName}}{{end}}
+{{template "contacts" .}}`,
+	}
+
+	expected := `example.br 20160301 a.dns.br
+handle:   XXXX
+roles:    registrant
+
+`
+
+	var w WriterMock
+	if err := domain.Print(&w); err != nil {
+		t.Fatal(err)
+	}
+
+	if string(w.Content) != expected {
+		for _, l := range diff(expected, string(w.Content)) {
+			t.Log(l)
+		}
+		t.Fatal("error")
+	}
+}
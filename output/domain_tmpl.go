@@ -35,7 +35,7 @@ changed:  {{.UpdatedAt | formatDate}}
 status:   {{.}}
 {{end}}\
 
-` + contactTmpl
+` + contactTmpl + redactionsTmplCall + noticesTmplCall
 	dateFormat = "20060102"
 )
 
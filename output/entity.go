@@ -2,8 +2,6 @@ package output
 
 import (
 	"io"
-	"strings"
-	"text/template"
 
 	"github.com/registrobr/rdap/protocol"
 )
@@ -15,6 +13,18 @@ type Entity struct {
 	UpdatedAt protocol.EventDate
 
 	ContactsInfos []contactInfo
+
+	// Template replaces the default template when defined
+	Template string
+
+	// HideNotices leaves the notices, remarks and links out of the output
+	HideNotices bool
+	Notices     *noticeSection
+
+	// Redacted lists the members of the response removed or changed by the
+	// server, as described in RFC 9537
+	Redacted   []Redaction
+	Redactions []RedactedField
 }
 
 func (e *Entity) AddContact(c contactInfo) {
@@ -32,16 +42,27 @@ func (e *Entity) setDates() {
 	}
 }
 
-func (e *Entity) Print(wr io.Writer) error {
+func (e *Entity) prepare() {
 	e.setDates()
 	var contactInfo contactInfo
 	contactInfo.setContact(*e.Entity)
 	e.ContactsInfos = append(e.ContactsInfos, contactInfo)
+	e.Redactions = applyRedactions(e.ContactsInfos, e.Redacted, true)
+
+	if !e.HideNotices {
+		e.Notices = newNoticeSection(e.Entity.Notices, e.Entity.Remarks, e.Entity.Links)
+	}
+}
 
-	t, err := template.New("entity template").
-		Funcs(genericFuncMap).
-		Parse(strings.ReplaceAll(contactTmpl, "\\\n", ""))
+func (e *Entity) Print(wr io.Writer) error {
+	e.prepare()
+
+	tmpl := contactTmpl + redactionsTmplCall + noticesTmplCall
+	if e.Template != "" {
+		tmpl = e.Template
+	}
 
+	t, err := newTemplate("entity template", tmpl)
 	if err != nil {
 		return err
 	}
@@ -7,6 +7,39 @@ import (
 	"github.com/registrobr/rdap/protocol"
 )
 
+// sharedTemplates are the blocks available to every template, as
+// {{template "contacts" .}}, {{template "notices" .Notices}} and
+// {{template "redactions" .Redactions}}
+var sharedTemplates = []struct {
+	name, text string
+}{
+	{"contacts", contactTmpl},
+	{"notices", noticeTmpl},
+	{"redacted", redactedTmpl},
+	{"redactions", redactionsTmpl},
+}
+
+// newTemplate parses the template text joining the lines that end with a
+// backslash, along with the shared blocks
+func newTemplate(name, text string, funcMaps ...template.FuncMap) (*template.Template, error) {
+	t := template.New(name).Funcs(genericFuncMap)
+	for _, funcMap := range funcMaps {
+		t = t.Funcs(funcMap)
+	}
+
+	if _, err := t.Parse(strings.ReplaceAll(text, "\\\n", "")); err != nil {
+		return nil, err
+	}
+
+	for _, shared := range sharedTemplates {
+		if _, err := t.New(shared.name).Parse(strings.ReplaceAll(shared.text, "\\\n", "")); err != nil {
+			return nil, err
+		}
+	}
+
+	return t, nil
+}
+
 var (
 	genericFuncMap = template.FuncMap{
 		"isDateDefined": func(time protocol.EventDate) bool {
@@ -2,8 +2,6 @@ package output
 
 import (
 	"io"
-	"strings"
-	"text/template"
 
 	"github.com/registrobr/rdap/protocol"
 )
@@ -13,6 +11,18 @@ type IPNetwork struct {
 	CreatedAt     protocol.EventDate
 	UpdatedAt     protocol.EventDate
 	ContactsInfos []contactInfo
+
+	// Template replaces the default template when defined
+	Template string
+
+	// HideNotices leaves the notices, remarks and links out of the output
+	HideNotices bool
+	Notices     *noticeSection
+
+	// Redacted lists the members of the response removed or changed by the
+	// server, as described in RFC 9537
+	Redacted   []Redaction
+	Redactions []RedactedField
 }
 
 func (i *IPNetwork) addContact(c contactInfo) {
@@ -38,16 +48,26 @@ func (i *IPNetwork) setDates() {
 	}
 }
 
-func (i *IPNetwork) Print(wr io.Writer) error {
+func (i *IPNetwork) prepare() {
 	i.setDates()
 	addContacts(i, i.IPNetwork.Entities)
 	filterContacts(i)
+	i.Redactions = applyRedactions(i.ContactsInfos, i.Redacted, false)
+
+	if !i.HideNotices {
+		i.Notices = newNoticeSection(i.IPNetwork.Notices, i.IPNetwork.Remarks, i.IPNetwork.Links)
+	}
+}
 
-	t, err := template.New("ipnetwork template").
-		Funcs(genericFuncMap).
-		Funcs(ipnetFuncMap).
-		Parse(strings.ReplaceAll(ipnetTmpl, "\\\n", ""))
+func (i *IPNetwork) Print(wr io.Writer) error {
+	i.prepare()
+
+	tmpl := ipnetTmpl
+	if i.Template != "" {
+		tmpl = i.Template
+	}
 
+	t, err := newTemplate("ipnetwork template", tmpl, ipnetFuncMap)
 	if err != nil {
 		return err
 	}
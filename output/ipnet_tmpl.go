@@ -63,7 +63,7 @@ created:       {{.CreatedAt | formatDate}}
 changed:       {{.UpdatedAt | formatDate}}
 {{end}}\
 
-` + contactTmpl
+` + contactTmpl + redactionsTmplCall + noticesTmplCall
 
 var (
 	ipnetFuncMap = template.FuncMap{
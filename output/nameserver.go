@@ -0,0 +1,79 @@
+package output
+
+import (
+	"io"
+
+	"github.com/registrobr/rdap/protocol"
+)
+
+type Nameserver struct {
+	Nameserver *protocol.Nameserver
+
+	CreatedAt protocol.EventDate
+	UpdatedAt protocol.EventDate
+
+	ContactsInfos []contactInfo
+
+	// Template replaces the default template when defined
+	Template string
+
+	// HideNotices leaves the notices, remarks and links out of the output
+	HideNotices bool
+	Notices     *noticeSection
+
+	// Redacted lists the members of the response removed or changed by the
+	// server, as described in RFC 9537
+	Redacted   []Redaction
+	Redactions []RedactedField
+}
+
+func (n *Nameserver) addContact(c contactInfo) {
+	n.ContactsInfos = append(n.ContactsInfos, c)
+}
+
+func (n *Nameserver) getContacts() []contactInfo {
+	return n.ContactsInfos
+}
+
+func (n *Nameserver) setContacts(c []contactInfo) {
+	n.ContactsInfos = c
+}
+
+func (n *Nameserver) setDates() {
+	for _, e := range n.Nameserver.Events {
+		switch e.Action {
+		case protocol.EventActionRegistration:
+			n.CreatedAt = e.Date
+		case protocol.EventActionLastChanged:
+			n.UpdatedAt = e.Date
+		}
+	}
+}
+
+func (n *Nameserver) prepare() {
+	n.setDates()
+	addContacts(n, n.Nameserver.Entities)
+	filterContacts(n)
+	n.Redactions = applyRedactions(n.ContactsInfos, n.Redacted, false)
+
+	// nameserver responses have no notices member in the protocol package
+	if !n.HideNotices {
+		n.Notices = newNoticeSection(nil, n.Nameserver.Remarks, n.Nameserver.Links)
+	}
+}
+
+func (n *Nameserver) Print(wr io.Writer) error {
+	n.prepare()
+
+	tmpl := nameserverTmpl
+	if n.Template != "" {
+		tmpl = n.Template
+	}
+
+	t, err := newTemplate("nameserver template", tmpl, domainFuncMap)
+	if err != nil {
+		return err
+	}
+
+	return t.Execute(wr, n)
+}
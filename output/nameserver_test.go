@@ -0,0 +1,81 @@
+package output
+
+import (
+	"testing"
+	"time"
+
+	"github.com/registrobr/rdap/protocol"
+)
+
+func TestNameserverPrint(t *testing.T) {
+	nameserver := Nameserver{
+		Nameserver: &protocol.Nameserver{
+			ObjectClassName: "nameserver",
+			Handle:          "NS-1",
+			LDHName:         "a.dns.br",
+			Status:          []protocol.Status{"active"},
+			IPAddresses: &protocol.IPAddresses{
+				V4: []string{"200.160.0.10"},
+				V6: []string{"2001:12ff::10"},
+			},
+			Events: []protocol.Event{
+				{
+					Action: protocol.EventActionRegistration,
+					Date:   protocol.Date(2015, 03, 01, 12, 00, 00, 00, time.UTC),
+				},
+				{
+					Action: protocol.EventActionLastChanged,
+					Date:   protocol.Date(2015, 03, 10, 14, 00, 00, 00, time.UTC),
+				},
+			},
+			Entities: []protocol.Entity{
+				{
+					ObjectClassName: "entity",
+					Handle:          "XXXX",
+					Roles:           []string{"technical"},
+					VCardArray: []any{
+						"vcard",
+						[]any{
+							[]any{"version", struct{}{}, "text", "4.0"},
+							[]any{"fn", struct{}{}, "text", "Joe User"},
+							[]any{"email", struct{ Type string }{Type: "work"}, "text", "joe.user@example.com"},
+						},
+					},
+				},
+				{
+					ObjectClassName: "entity",
+					Handle:          "XXXX",
+					Roles:           []string{"abuse"},
+				},
+			},
+		},
+	}
+
+	expected := `
+nserver:  a.dns.br
+handle:   NS-1
+ipv4:     200.160.0.10
+ipv6:     2001:12ff::10
+created:  20150301
+changed:  20150310
+status:   active
+
+handle:   XXXX
+roles:    technical, abuse
+person:   Joe User
+e-mail:   joe.user@example.com
+
+`
+
+	var w WriterMock
+	if err := nameserver.Print(&w); err != nil {
+		t.Fatal(err)
+	}
+
+	if string(w.Content) != expected {
+		for _, l := range diff(expected, string(w.Content)) {
+			t.Log(l)
+		}
+		t.Fatal("error")
+	}
+}
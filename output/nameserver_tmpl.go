@@ -0,0 +1,34 @@
+package output
+
+const nameserverTmpl = `
+nserver:  {{.Nameserver.LDHName}}
+{{if ne .Nameserver.Handle ""}}\
+handle:   {{.Nameserver.Handle}}
+{{end}}\
+{{if .Nameserver.IPAddresses}}\
+{{range .Nameserver.IPAddresses.V4}}\
+ipv4:     {{.}}
+{{end}}\
+{{range .Nameserver.IPAddresses.V6}}\
+ipv6:     {{.}}
+{{end}}\
+{{end}}\
+{{$lastCheck := nsLastCheck .Nameserver.Events}}\
+{{if (isDateDefined $lastCheck)}}\
+nsstat:   {{$lastCheck | formatDate}} {{nsStatus .Nameserver.Events}}
+{{end}}\
+{{$lastOK := nsLastOK .Nameserver.Events}}\
+{{if (isDateDefined $lastOK)}}\
+nslastaa: {{$lastOK | formatDate}}
+{{end}}\
+{{if (isDateDefined .CreatedAt)}}\
+created:  {{.CreatedAt | formatDate}}
+{{end}}\
+{{if (isDateDefined .UpdatedAt)}}\
+changed:  {{.UpdatedAt | formatDate}}
+{{end}}\
+{{range .Nameserver.Status}}\
+status:   {{.}}
+{{end}}\
+
+` + contactTmpl + redactionsTmplCall + noticesTmplCall
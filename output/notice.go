@@ -0,0 +1,95 @@
+package output
+
+import (
+	"strings"
+
+	"github.com/registrobr/rdap/protocol"
+)
+
+// noticeWidth is the maximum length of a notice or remark line, not counting
+// the label
+const noticeWidth = 70
+
+// noticeInfo stores a notice or remark ready to be printed, with the
+// description wrapped in lines of at most noticeWidth characters
+type noticeInfo struct {
+	Title       string
+	Description []string
+	Links       []string
+}
+
+// noticeSection stores the notices, remarks and links of an object, as
+// described in RFC 9083, sections 4.2 and 4.3. The links to the object itself
+// are left out, as they only repeat the query
+type noticeSection struct {
+	Notices []noticeInfo
+	Remarks []noticeInfo
+	Links   []protocol.Link
+}
+
+func newNoticeInfo(title string, description []string, links []protocol.Link) noticeInfo {
+	n := noticeInfo{Title: title}
+
+	for _, line := range description {
+		n.Description = append(n.Description, wrap(line, noticeWidth)...)
+	}
+
+	for _, link := range links {
+		n.Links = append(n.Links, link.Href)
+	}
+
+	return n
+}
+
+// newNoticeSection returns nil when there is nothing to print, so the section
+// is omitted by the templates
+func newNoticeSection(notices []protocol.Notice, remarks []protocol.Remark, links []protocol.Link) *noticeSection {
+	var s noticeSection
+
+	for _, notice := range notices {
+		s.Notices = append(s.Notices, newNoticeInfo(notice.Title, notice.Description, notice.Links))
+	}
+
+	for _, remark := range remarks {
+		s.Remarks = append(s.Remarks, newNoticeInfo(remark.Title, remark.Description, remark.Links))
+	}
+
+	for _, link := range links {
+		if link.Rel != "self" {
+			s.Links = append(s.Links, link)
+		}
+	}
+
+	if len(s.Notices) == 0 && len(s.Remarks) == 0 && len(s.Links) == 0 {
+		return nil
+	}
+
+	return &s
+}
+
+// wrap breaks the text in lines of at most width characters, splitting only
+// on spaces. Words longer than width are kept whole in their own line
+func wrap(text string, width int) []string {
+	var (
+		lines []string
+		line  string
+	)
+
+	for _, word := range strings.Fields(text) {
+		switch {
+		case line == "":
+			line = word
+		case len([]rune(line))+1+len([]rune(word)) <= width:
+			line += " " + word
+		default:
+			lines = append(lines, line)
+			line = word
+		}
+	}
+
+	if line != "" {
+		lines = append(lines, line)
+	}
+
+	return lines
+}
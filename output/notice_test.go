@@ -0,0 +1,119 @@
+package output
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/registrobr/rdap/protocol"
+)
+
+func TestNoticesPrint(t *testing.T) {
+	domain := &protocol.Domain{
+		ObjectClassName: "domain",
+		LDHName:         "example.br",
+		Notices: []protocol.Notice{
+			{
+				Title: "Terms of Use",
+				Description: []string{
+					"Service subject to the terms of use of the registry, which forbid the use of the data for sending unsolicited messages.",
+				},
+				Links: []protocol.Link{
+					{Rel: "alternate", Href: "https://registro.br/tos/", Type: "text/html"},
+				},
+			},
+		},
+		Remarks: []protocol.Remark{
+			{Description: []string{"Domain reserved for documentation"}},
+		},
+		Links: []protocol.Link{
+			{Rel: "self", Href: "https://rdap.registro.br/domain/example.br"},
+			{Rel: "related", Href: "https://rdap.example.com/domain/example.br"},
+		},
+	}
+
+	data := []struct {
+		description string
+		hideNotices bool
+		expected    string
+	}{
+		{
+			description: "it should print the notices, remarks and links",
+			expected: `
+domain:   example.br
+
+notice:   Terms of Use
+notice:   Service subject to the terms of use of the registry, which forbid the
+notice:   use of the data for sending unsolicited messages.
+notice:   https://registro.br/tos/
+
+remark:   Domain reserved for documentation
+
+link:     https://rdap.example.com/domain/example.br (related)
+
+`,
+		},
+		{
+			description: "it should leave the notices, remarks and links out",
+			hideNotices: true,
+			expected: `
+domain:   example.br
+
+`,
+		},
+	}
+
+	for _, item := range data {
+		printer := Domain{Domain: domain, HideNotices: item.hideNotices}
+
+		var w WriterMock
+		if err := printer.Print(&w); err != nil {
+			t.Fatal(err)
+		}
+
+		if string(w.Content) != item.expected {
+			for _, l := range diff(item.expected, string(w.Content)) {
+				t.Log(l)
+			}
+			t.Errorf("%s: unexpected output", item.description)
+		}
+	}
+}
+
+func TestWrap(t *testing.T) {
+	data := []struct {
+		description string
+		text        string
+		width       int
+		expected    []string
+	}{
+		{
+			description: "it should keep short lines",
+			text:        "short line",
+			width:       20,
+			expected:    []string{"short line"},
+		},
+		{
+			description: "it should break on spaces",
+			text:        "one two three  four",
+			width:       9,
+			expected:    []string{"one two", "three", "four"},
+		},
+		{
+			description: "it should keep long words whole",
+			text:        "see https://registro.br/tos/",
+			width:       10,
+			expected:    []string{"see", "https://registro.br/tos/"},
+		},
+		{
+			description: "it should ignore empty lines",
+			text:        "  ",
+			width:       10,
+		},
+	}
+
+	for _, item := range data {
+		if lines := wrap(item.text, item.width); !reflect.DeepEqual(lines, item.expected) {
+			t.Errorf("%s: expected %q and got %q", item.description, item.expected, lines)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package output
+
+// noticesTmplCall includes the notices section in the default templates
+const noticesTmplCall = `{{template "notices" .Notices}}`
+
+const noticeTmpl = `{{with .}}\
+{{range .Notices}}\
+{{if ne .Title ""}}\
+notice:   {{.Title}}
+{{end}}\
+{{range .Description}}\
+notice:   {{.}}
+{{end}}\
+{{range .Links}}\
+notice:   {{.}}
+{{end}}\
+
+{{end}}\
+{{range .Remarks}}\
+{{if ne .Title ""}}\
+remark:   {{.Title}}
+{{end}}\
+{{range .Description}}\
+remark:   {{.}}
+{{end}}\
+{{range .Links}}\
+remark:   {{.}}
+{{end}}\
+
+{{end}}\
+{{if len .Links}}\
+{{range .Links}}\
+link:     {{.Href}}{{if ne .Rel ""}} ({{.Rel}}){{end}}
+{{end}}\
+
+{{end}}\
+{{end}}`
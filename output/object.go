@@ -0,0 +1,107 @@
+package output
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/registrobr/rdap/protocol"
+)
+
+// commonMembers stores the members that every RDAP object class can have,
+// as described in RFC 9083, sections 4 and 5
+type commonMembers struct {
+	ObjectClassName string            `json:"objectClassName"`
+	Handle          string            `json:"handle,omitempty"`
+	Status          []string          `json:"status,omitempty"`
+	Events          []protocol.Event  `json:"events,omitempty"`
+	Links           []protocol.Link   `json:"links,omitempty"`
+	Remarks         []protocol.Remark `json:"remarks,omitempty"`
+	Notices         []protocol.Notice `json:"notices,omitempty"`
+	Entities        []protocol.Entity `json:"entities,omitempty"`
+	Redacted        []Redaction       `json:"redacted,omitempty"`
+}
+
+// Object prints any RDAP object using only the members common to all object
+// classes. It is used for the object classes without a specific printer, so
+// new object classes are still readable
+type Object struct {
+	Object any
+
+	Common        commonMembers
+	ContactsInfos []contactInfo
+
+	// Template replaces the default template when defined
+	Template string
+
+	// HideNotices leaves the notices, remarks and links out of the output
+	HideNotices bool
+	Notices     *noticeSection
+
+	// Redacted lists the members of the response removed or changed by the
+	// server, as described in RFC 9537
+	Redacted   []Redaction
+	Redactions []RedactedField
+}
+
+func (o *Object) addContact(c contactInfo) {
+	o.ContactsInfos = append(o.ContactsInfos, c)
+}
+
+func (o *Object) getContacts() []contactInfo {
+	return o.ContactsInfos
+}
+
+func (o *Object) setContacts(c []contactInfo) {
+	o.ContactsInfos = c
+}
+
+func (o *Object) setCommon() error {
+	content, ok := o.Object.(json.RawMessage)
+	if !ok {
+		var err error
+		if content, err = json.Marshal(o.Object); err != nil {
+			return err
+		}
+	}
+
+	return json.Unmarshal(content, &o.Common)
+}
+
+func (o *Object) prepare() error {
+	if err := o.setCommon(); err != nil {
+		return err
+	}
+
+	addContacts(o, o.Common.Entities)
+	filterContacts(o)
+
+	redacted := o.Redacted
+	if len(redacted) == 0 {
+		redacted = o.Common.Redacted
+	}
+	o.Redactions = applyRedactions(o.ContactsInfos, redacted, false)
+
+	if !o.HideNotices {
+		o.Notices = newNoticeSection(o.Common.Notices, o.Common.Remarks, o.Common.Links)
+	}
+
+	return nil
+}
+
+func (o *Object) Print(wr io.Writer) error {
+	if err := o.prepare(); err != nil {
+		return err
+	}
+
+	tmpl := objectTmpl
+	if o.Template != "" {
+		tmpl = o.Template
+	}
+
+	t, err := newTemplate("object template", tmpl)
+	if err != nil {
+		return err
+	}
+
+	return t.Execute(wr, o)
+}
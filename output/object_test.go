@@ -0,0 +1,74 @@
+package output
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestObjectPrint(t *testing.T) {
+	response := json.RawMessage(`{
+  "objectClassName": "registrar",
+  "handle": "REG-123",
+  "status": ["active"],
+  "events": [
+    {"eventAction": "registration", "eventDate": "2015-03-01T12:00:00Z", "eventActor": "XXXX"}
+  ],
+  "links": [
+    {"href": "https://rdap.example.com/registrar/REG-123", "rel": "self"}
+  ],
+  "remarks": [
+    {"title": "Description", "description": ["Example registrar"]}
+  ],
+  "entities": [
+    {
+      "objectClassName": "entity",
+      "handle": "XXXX",
+      "roles": ["abuse"],
+      "vcardArray": ["vcard", [["version", {}, "text", "4.0"], ["email", {}, "text", "abuse@example.com"]]]
+    }
+  ],
+  "unknownMember": {"value": 1}
+}`)
+
+	expected := `
+% unrecognized object class “registrar”
+handle:   REG-123
+status:   active
+event:    20150301 registration (XXXX)
+
+handle:   XXXX
+roles:    abuse
+e-mail:   abuse@example.com
+
+remark:   Description
+remark:   Example registrar
+
+`
+
+	object := Object{Object: response}
+
+	var w WriterMock
+	if err := object.Print(&w); err != nil {
+		t.Fatal(err)
+	}
+
+	if string(w.Content) != expected {
+		for _, l := range diff(expected, string(w.Content)) {
+			t.Log(l)
+		}
+		t.Fatal("error")
+	}
+}
+
+func TestObjectFallback(t *testing.T) {
+	object := map[string]any{"objectClassName": "registrar"}
+
+	printer, err := NewPrinter(FormatDefault, &Result{Object: object}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := printer.(*Object); !ok {
+		t.Fatalf("expected the object printer and got %T", printer)
+	}
+}
@@ -0,0 +1,19 @@
+package output
+
+const objectTmpl = `
+{{if ne .Common.ObjectClassName ""}}\
+% unrecognized object class “{{.Common.ObjectClassName}}”
+{{else}}\
+% object without class
+{{end}}\
+{{if ne .Common.Handle ""}}\
+handle:   {{.Common.Handle}}
+{{end}}\
+{{range .Common.Status}}\
+status:   {{.}}
+{{end}}\
+{{range .Common.Events}}\
+event:    {{.Date | formatDate}} {{.Action}}{{if ne .Actor ""}} ({{.Actor}}){{end}}
+{{end}}\
+
+` + contactTmpl + redactionsTmplCall + noticesTmplCall
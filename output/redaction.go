@@ -0,0 +1,226 @@
+package output
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Redaction methods, as described in RFC 9537, section 3
+const (
+	RedactionRemoval          = "removal"
+	RedactionEmptyValue       = "emptyValue"
+	RedactionPartialValue     = "partialValue"
+	RedactionReplacementValue = "replacementValue"
+)
+
+// Redaction describes a member of the response that the server removed or
+// changed, as listed in the “redacted” member of RFC 9537, section 4.2
+type Redaction struct {
+	Name            RedactionText  `json:"name"`
+	PrePath         string         `json:"prePath,omitempty"`
+	PostPath        string         `json:"postPath,omitempty"`
+	ReplacementPath string         `json:"replacementPath,omitempty"`
+	PathLang        string         `json:"pathLang,omitempty"`
+	Method          string         `json:"method,omitempty"`
+	Reason          *RedactionText `json:"reason,omitempty"`
+}
+
+// RedactionText is a registered type or a free description, used for the
+// name and the reason of a redaction
+type RedactionText struct {
+	Type        string `json:"type,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+func (r RedactionText) String() string {
+	if r.Type != "" {
+		return r.Type
+	}
+
+	return r.Description
+}
+
+// method returns the redaction method, that defaults to removal when the
+// server doesn't inform it
+func (r Redaction) method() string {
+	if r.Method == "" {
+		return RedactionRemoval
+	}
+
+	return r.Method
+}
+
+// path returns the JSONPath expression that points to the redacted member.
+// Removed members only have the path in the response before the redaction
+func (r Redaction) path() string {
+	if r.method() != RedactionRemoval && r.PostPath != "" {
+		return r.PostPath
+	}
+
+	return r.PrePath
+}
+
+var (
+	redactionMethods = map[string]string{
+		RedactionRemoval:          "removed",
+		RedactionEmptyValue:       "emptied",
+		RedactionPartialValue:     "partially replaced",
+		RedactionReplacementValue: "replaced",
+	}
+
+	// redactionLabels are the labels of the contact block for each vCard
+	// property that may be redacted
+	redactionLabels = map[string]string{
+		"fn":    "person",
+		"org":   "org",
+		"email": "e-mail",
+		"adr":   "address",
+		"tel":   "phone",
+	}
+
+	// redactionNameRoles and redactionNameProperties map the words of the
+	// names registered for redactions (like “Registrant Email” or “Tech
+	// Phone”) to roles and vCard properties, used when the path can't be
+	// understood
+	redactionNameRoles = map[string]string{
+		"registrant": "registrant",
+		"admin":      "administrative",
+		"tech":       "technical",
+		"billing":    "billing",
+	}
+	redactionNameProperties = map[string]string{
+		"name":         "fn",
+		"organization": "org",
+		"street":       "adr",
+		"city":         "adr",
+		"postal code":  "adr",
+		"email":        "email",
+		"phone":        "tel",
+		"phone ext":    "tel",
+		"fax":          "tel",
+		"fax ext":      "tel",
+	}
+
+	redactionRoleRX     = regexp.MustCompile(`@\.roles[^'"]*['"]([a-z ]+)['"]`)
+	redactionPropertyRX = regexp.MustCompile(`@\[0\]\s*==\s*['"]([a-z]+)['"]`)
+)
+
+// RedactedField is a redaction in the terms of the printed contact block, also
+// used by the summary
+type RedactedField struct {
+	// Role of the redacted contact, empty for the queried entity itself
+	Role     string `json:"role,omitempty"`
+	Property string `json:"property,omitempty"`
+	Label    string `json:"field,omitempty"`
+	Name     string `json:"name,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+	Method   string `json:"method"`
+}
+
+// Action describes what the server did to the field, as shown in the contact
+// block
+func (f RedactedField) Action() string {
+	if action, ok := redactionMethods[f.Method]; ok {
+		return action
+	}
+
+	return f.Method
+}
+
+// newRedactedField finds the contact role and the vCard property of the
+// redaction. Only the usual JSONPath expressions are understood, that select
+// the entities by role and the vCard properties by name, falling back to the
+// registered redaction names
+func newRedactedField(r Redaction) RedactedField {
+	field := RedactedField{
+		Name:   r.Name.String(),
+		Method: r.method(),
+	}
+
+	if r.Reason != nil {
+		field.Reason = r.Reason.String()
+	}
+
+	if path := r.path(); path != "" {
+		roles := redactionRoleRX.FindAllStringSubmatch(path, -1)
+		if len(roles) > 0 {
+			field.Role = roles[len(roles)-1][1]
+		}
+
+		// a contact selected in a way that isn't understood can't be told
+		// apart from the queried entity itself
+		property := redactionPropertyRX.FindStringSubmatch(path)
+		if len(property) > 0 && (len(roles) > 0 || !strings.Contains(path, "entities")) {
+			field.Property = property[1]
+		}
+	}
+
+	if field.Property == "" && r.Name.Type != "" {
+		name := strings.ToLower(r.Name.Type)
+		for prefix, role := range redactionNameRoles {
+			property, ok := redactionNameProperties[strings.TrimPrefix(name, prefix+" ")]
+			if strings.HasPrefix(name, prefix+" ") && ok {
+				field.Role = role
+				field.Property = property
+			}
+		}
+	}
+
+	field.Label = redactionLabels[field.Property]
+	return field
+}
+
+// applyRedactions marks the redacted fields in the contacts that play the
+// role of the redaction. When self is set the contacts are the queried entity
+// itself, that also receives the redactions without a role. It returns the
+// redactions that don't match any contact field, like the removal of a whole
+// contact or of members of the object
+func applyRedactions(contacts []contactInfo, redactions []Redaction, self bool) []RedactedField {
+	var unmatched []RedactedField
+
+	for _, redaction := range redactions {
+		field := newRedactedField(redaction)
+		if field.Label == "" {
+			unmatched = append(unmatched, field)
+			continue
+		}
+
+		matched := false
+		for i := range contacts {
+			if field.Role == "" && self || field.Role != "" && slices.Contains(contacts[i].Roles, field.Role) {
+				contacts[i].redact(field)
+				matched = true
+			}
+		}
+
+		if !matched {
+			unmatched = append(unmatched, field)
+		}
+	}
+
+	return unmatched
+}
+
+// redact records the redacted field, dropping the empty values left by the
+// server in its place
+func (c *contactInfo) redact(field RedactedField) {
+	c.Redactions = append(c.Redactions, field)
+
+	nonEmpty := func(values []string) []string {
+		return slices.DeleteFunc(values, func(value string) bool {
+			return strings.TrimSpace(value) == ""
+		})
+	}
+
+	switch field.Property {
+	case "fn":
+		c.Persons = nonEmpty(c.Persons)
+	case "email":
+		c.Emails = nonEmpty(c.Emails)
+	case "adr":
+		c.Addresses = nonEmpty(c.Addresses)
+	case "tel":
+		c.Phones = nonEmpty(c.Phones)
+	}
+}
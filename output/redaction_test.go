@@ -0,0 +1,161 @@
+package output
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/registrobr/rdap/protocol"
+)
+
+func TestNewRedactedField(t *testing.T) {
+	data := []struct {
+		description string
+		redaction   Redaction
+		expected    RedactedField
+	}{
+		{
+			description: "it should find the role and the property in the path",
+			redaction: Redaction{
+				Name:    RedactionText{Type: "Registrant Email"},
+				PrePath: "$.entities[?(@.roles[0]=='registrant')].vcardArray[1][?(@[0]=='email')]",
+				Reason:  &RedactionText{Type: "Server policy"},
+			},
+			expected: RedactedField{
+				Role:     "registrant",
+				Property: "email",
+				Label:    "e-mail",
+				Name:     "Registrant Email",
+				Reason:   "Server policy",
+				Method:   RedactionRemoval,
+			},
+		},
+		{
+			description: "it should use the path after the redaction for empty values",
+			redaction: Redaction{
+				Name:     RedactionText{Description: "Technical contact phone"},
+				PrePath:  "$.invalid",
+				PostPath: "$.entities[?(@.roles[0]=='technical')].vcardArray[1][?(@[0]=='tel')][3]",
+				Method:   RedactionEmptyValue,
+			},
+			expected: RedactedField{
+				Role:     "technical",
+				Property: "tel",
+				Label:    "phone",
+				Name:     "Technical contact phone",
+				Method:   RedactionEmptyValue,
+			},
+		},
+		{
+			description: "it should use the registered name when the path isn't understood",
+			redaction: Redaction{
+				Name:    RedactionText{Type: "Tech Name"},
+				PrePath: "$.entities[0].vcardArray[1][?(@[0]=='fn')]",
+				Method:  RedactionReplacementValue,
+			},
+			expected: RedactedField{
+				Role:     "technical",
+				Property: "fn",
+				Label:    "person",
+				Name:     "Tech Name",
+				Method:   RedactionReplacementValue,
+			},
+		},
+		{
+			description: "it should keep the queried entity properties without role",
+			redaction: Redaction{
+				Name:    RedactionText{Description: "Name"},
+				PrePath: "$.vcardArray[1][?(@[0]=='fn')]",
+				Method:  RedactionPartialValue,
+			},
+			expected: RedactedField{
+				Property: "fn",
+				Label:    "person",
+				Name:     "Name",
+				Method:   RedactionPartialValue,
+			},
+		},
+		{
+			description: "it should not find a field for other members",
+			redaction: Redaction{
+				Name:    RedactionText{Type: "Registry Domain ID"},
+				PrePath: "$.handle",
+			},
+			expected: RedactedField{
+				Name:   "Registry Domain ID",
+				Method: RedactionRemoval,
+			},
+		},
+	}
+
+	for _, item := range data {
+		if field := newRedactedField(item.redaction); !reflect.DeepEqual(field, item.expected) {
+			t.Errorf("%s: expected %+v and got %+v", item.description, item.expected, field)
+		}
+	}
+}
+
+func TestDomainPrintWithRedactions(t *testing.T) {
+	domain := Domain{
+		Domain: &protocol.Domain{
+			ObjectClassName: "domain",
+			LDHName:         "example.br",
+			Entities: []protocol.Entity{
+				{
+					ObjectClassName: "entity",
+					Handle:          "XXXX",
+					Roles:           []string{"registrant"},
+					VCardArray: []any{
+						"vcard",
+						[]any{
+							[]any{"version", struct{}{}, "text", "4.0"},
+							[]any{"fn", struct{}{}, "text", ""},
+							[]any{"tel", struct{}{}, "uri", "tel:+55-11-5509-3506"},
+						},
+					},
+				},
+			},
+		},
+		Redacted: []Redaction{
+			{
+				Name:     RedactionText{Type: "Registrant Name"},
+				PostPath: "$.entities[?(@.roles[0]=='registrant')].vcardArray[1][?(@[0]=='fn')][3]",
+				Method:   RedactionEmptyValue,
+				Reason:   &RedactionText{Type: "Server policy"},
+			},
+			{
+				Name:    RedactionText{Type: "Registrant Email"},
+				PrePath: "$.entities[?(@.roles[0]=='registrant')].vcardArray[1][?(@[0]=='email')]",
+			},
+			{
+				Name:    RedactionText{Type: "Registry Domain ID"},
+				PrePath: "$.handle",
+				Reason:  &RedactionText{Description: "Not published"},
+			},
+		},
+	}
+
+	expected := `
+domain:   example.br
+
+handle:   XXXX
+roles:    registrant
+phone:    tel:+55-11-5509-3506
+person:   [REDACTED: Server policy] (emptied)
+e-mail:   [REDACTED] (removed)
+
+redacted: Registry Domain ID [REDACTED: Not published] (removed)
+
+`
+
+	var w WriterMock
+	if err := domain.Print(&w); err != nil {
+		t.Fatal(err)
+	}
+
+	if string(w.Content) != expected {
+		for _, l := range diff(expected, string(w.Content)) {
+			t.Log(l)
+		}
+		t.Fatal("error")
+	}
+}
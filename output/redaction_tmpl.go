@@ -0,0 +1,16 @@
+package output
+
+const (
+	redactedTmpl = `{{if ne .Reason ""}}[REDACTED: {{.Reason}}]{{else}}[REDACTED]{{end}} ({{.Action}})`
+
+	redactionsTmpl = `{{if len .}}\
+{{range .}}\
+redacted: {{if ne .Name ""}}{{.Name}} {{end}}{{template "redacted" .}}
+{{end}}\
+
+{{end}}`
+
+	// redactionsTmplCall includes the redactions that don't belong to any
+	// contact in the default templates
+	redactionsTmplCall = `{{template "redactions" .Redactions}}`
+)
@@ -0,0 +1,243 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/registrobr/rdap/protocol"
+)
+
+// List of output formats provided by this package
+const (
+	FormatDefault = "default"
+	FormatRaw     = "raw"
+	FormatJSONL   = "jsonl"
+	FormatSummary = "summary-json"
+)
+
+// Options stores the user preferences that printers should follow
+type Options struct {
+	// Templates replaces the default templates of the object types. The keys
+	// are the query types “domain”, “autnum”, “ip”, “entity” and “nameserver”,
+	// and “object” for the object classes without a specific printer
+	Templates map[string]string
+
+	// HideNotices leaves the notices, remarks and links of the objects out of
+	// the default output
+	HideNotices bool
+}
+
+// Factory builds the printer of a query result
+type Factory func(r *Result, opts Options) Printer
+
+type registryKey struct {
+	format string
+	object reflect.Type
+}
+
+var (
+	registryLock sync.RWMutex
+	registry     = make(map[registryKey]Factory)
+	resultType   = reflect.TypeOf((*Result)(nil))
+)
+
+// Register associates a printer factory with an output format and an object
+// type, identified by a value of the type, like (*protocol.Domain)(nil). A
+// nil object registers the factory for every object type without a specific
+// factory in the format. A (*Result)(nil) object registers the factory for
+// all results of the format, including the ones of failed queries. Registering
+// the same format and object type again replaces the previous factory
+func Register(format string, object any, factory Factory) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+
+	registry[registryKey{format: format, object: reflect.TypeOf(object)}] = factory
+}
+
+// Formats returns the names of all registered output formats
+func Formats() []string {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+
+	found := make(map[string]bool)
+	var formats []string
+
+	for key := range registry {
+		if !found[key.format] {
+			found[key.format] = true
+			formats = append(formats, key.format)
+		}
+	}
+
+	sort.Strings(formats)
+	return formats
+}
+
+// NewPrinter returns the printer of the result in the given format. The
+// factory registered for the object type is preferred, then the one for any
+// object type, and at last the one for any result. Objects that the format
+// doesn't support are printed by the default format, so they are readable
+// even in text formats registered for some object types only. The JSON
+// formats register a factory for any object type, so they never fall back.
+// Results of failed queries can only be printed by factories registered for
+// any result
+func NewPrinter(format string, r *Result, opts Options) (Printer, error) {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+
+	if !registered(format) {
+		return nil, fmt.Errorf("unknown output format “%s”", format)
+	}
+
+	var keys []registryKey
+	if r.Err == nil {
+		keys = append(keys,
+			registryKey{format: format, object: reflect.TypeOf(r.Object)},
+			registryKey{format: format},
+		)
+	}
+	keys = append(keys, registryKey{format: format, object: resultType})
+
+	if r.Err == nil {
+		keys = append(keys,
+			registryKey{format: FormatDefault, object: reflect.TypeOf(r.Object)},
+			registryKey{format: FormatDefault},
+		)
+	}
+
+	for _, key := range keys {
+		if factory, ok := registry[key]; ok {
+			return factory(r, opts), nil
+		}
+	}
+
+	if r.Err != nil {
+		return nil, fmt.Errorf("output format “%s” can't print failures", format)
+	}
+
+	return nil, fmt.Errorf("output format “%s” doesn't support %T objects", format, r.Object)
+}
+
+// registered tells if any factory was registered for the format. The
+// registry must be locked by the caller
+func registered(format string) bool {
+	for key := range registry {
+		if key.format == format {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Raw prints the object as returned by the RDAP server, in an indented JSON
+// document
+type Raw struct {
+	Object any
+}
+
+func (r *Raw) Print(wr io.Writer) error {
+	output, err := json.MarshalIndent(r.Object, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(wr, string(output))
+	return err
+}
+
+func init() {
+	Register(FormatDefault, (*protocol.AS)(nil), func(r *Result, opts Options) Printer {
+		return &AS{
+			AS:          r.Object.(*protocol.AS),
+			Template:    opts.Templates["autnum"],
+			HideNotices: opts.HideNotices,
+			Redacted:    r.Redacted,
+		}
+	})
+
+	Register(FormatDefault, (*protocol.Domain)(nil), func(r *Result, opts Options) Printer {
+		return &Domain{
+			Domain:      r.Object.(*protocol.Domain),
+			Template:    opts.Templates["domain"],
+			HideNotices: opts.HideNotices,
+			Redacted:    r.Redacted,
+		}
+	})
+
+	Register(FormatDefault, (*protocol.Entity)(nil), func(r *Result, opts Options) Printer {
+		return &Entity{
+			Entity:      r.Object.(*protocol.Entity),
+			Template:    opts.Templates["entity"],
+			HideNotices: opts.HideNotices,
+			Redacted:    r.Redacted,
+		}
+	})
+
+	Register(FormatDefault, (*protocol.IPNetwork)(nil), func(r *Result, opts Options) Printer {
+		return &IPNetwork{
+			IPNetwork:   r.Object.(*protocol.IPNetwork),
+			Template:    opts.Templates["ip"],
+			HideNotices: opts.HideNotices,
+			Redacted:    r.Redacted,
+		}
+	})
+
+	Register(FormatDefault, (*protocol.Nameserver)(nil), func(r *Result, opts Options) Printer {
+		return &Nameserver{
+			Nameserver:  r.Object.(*protocol.Nameserver),
+			Template:    opts.Templates["nameserver"],
+			HideNotices: opts.HideNotices,
+			Redacted:    r.Redacted,
+		}
+	})
+
+	Register(FormatDefault, (*SearchResults)(nil), func(r *Result, opts Options) Printer {
+		return &Search{
+			Results:     r.Object.(*SearchResults),
+			Templates:   opts.Templates,
+			HideNotices: opts.HideNotices,
+		}
+	})
+
+	Register(FormatDefault, nil, func(r *Result, opts Options) Printer {
+		return &Object{
+			Object:      r.Object,
+			Template:    opts.Templates["object"],
+			HideNotices: opts.HideNotices,
+			Redacted:    r.Redacted,
+		}
+	})
+
+	Register(FormatRaw, nil, func(r *Result, opts Options) Printer {
+		return &Raw{Object: r.Object}
+	})
+
+	Register(FormatJSONL, (*Result)(nil), func(r *Result, opts Options) Printer {
+		return &JSONLine{Result: r}
+	})
+
+	summary := func(r *Result, opts Options) Printer {
+		return &JSONSummary{Object: r.Object, Redacted: r.Redacted, Referrals: r.Referrals}
+	}
+
+	Register(FormatSummary, (*protocol.AS)(nil), summary)
+	Register(FormatSummary, (*protocol.Domain)(nil), summary)
+	Register(FormatSummary, (*protocol.Entity)(nil), summary)
+	Register(FormatSummary, (*protocol.IPNetwork)(nil), summary)
+	Register(FormatSummary, (*protocol.Nameserver)(nil), summary)
+
+	// the objects without a summary are kept as JSON, instead of falling back
+	// to the text of the default output
+	Register(FormatSummary, nil, func(r *Result, opts Options) Printer {
+		return &Raw{Object: r.Object}
+	})
+
+	Register(FormatSummary, (*Result)(nil), func(r *Result, opts Options) Printer {
+		return &JSONSummaryError{Result: r}
+	})
+}
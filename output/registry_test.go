@@ -0,0 +1,148 @@
+package output
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/registrobr/rdap/protocol"
+)
+
+type csvPrinter struct {
+	result *Result
+}
+
+func (c *csvPrinter) Print(wr io.Writer) error {
+	domain := c.result.Object.(*protocol.Domain)
+	_, err := fmt.Fprintf(wr, "%s,%s\n", c.result.Query, domain.LDHName)
+	return err
+}
+
+func TestRegister(t *testing.T) {
+	Register("test-csv", (*protocol.Domain)(nil), func(r *Result, opts Options) Printer {
+		return &csvPrinter{result: r}
+	})
+
+	found := false
+	for _, format := range Formats() {
+		if format == "test-csv" {
+			found = true
+		}
+	}
+
+	if !found {
+		t.Fatal("registered format not listed")
+	}
+
+	result := Result{
+		Query:  "EXAMPLE.BR",
+		Object: &protocol.Domain{LDHName: "example.br"},
+	}
+
+	printer, err := NewPrinter("test-csv", &result, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var w WriterMock
+	if err := printer.Print(&w); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := "EXAMPLE.BR,example.br\n"; string(w.Content) != expected {
+		t.Fatalf("expected “%s” and got “%s”", expected, string(w.Content))
+	}
+
+	// the object types the format doesn't support fall back to the default
+	// format
+	printer, err = NewPrinter("test-csv", &Result{Object: &protocol.AS{}}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := printer.(*AS); !ok {
+		t.Fatalf("expected the default printer of the object type and got %T", printer)
+	}
+}
+
+func TestNewPrinter(t *testing.T) {
+	failure := Result{Query: "example.br", Err: errors.New("not found")}
+
+	data := []struct {
+		description string
+		format      string
+		result      Result
+		expected    Printer
+		expectError bool
+	}{
+		{
+			description: "it should find the printer of the object type",
+			format:      FormatDefault,
+			result:      Result{Object: &protocol.Domain{}},
+			expected:    &Domain{Domain: &protocol.Domain{}},
+		},
+		{
+			description: "it should find the printer for any object type",
+			format:      FormatRaw,
+			result:      Result{Object: &protocol.Nameserver{}},
+			expected:    &Raw{Object: &protocol.Nameserver{}},
+		},
+		{
+			description: "it should find the printer for any result",
+			format:      FormatJSONL,
+			result:      failure,
+			expected:    &JSONLine{Result: &failure},
+		},
+		{
+			description: "it should keep the search results of the summary as JSON",
+			format:      FormatSummary,
+			result:      Result{Object: &SearchResults{}},
+			expected:    &Raw{Object: &SearchResults{}},
+		},
+		{
+			description: "it should keep the unknown object types of the summary as JSON",
+			format:      FormatSummary,
+			result:      Result{Object: map[string]any{"objectClassName": "mark"}},
+			expected:    &Raw{},
+		},
+		{
+			description: "it should print the failures of the summary as JSON",
+			format:      FormatSummary,
+			result:      failure,
+			expected:    &JSONSummaryError{Result: &failure},
+		},
+		{
+			description: "it should not print failures with an object printer",
+			format:      FormatDefault,
+			result:      failure,
+			expectError: true,
+		},
+		{
+			description: "it should detect an unknown format",
+			format:      "unknown",
+			result:      Result{Object: &protocol.Domain{}},
+			expectError: true,
+		},
+	}
+
+	for _, item := range data {
+		printer, err := NewPrinter(item.format, &item.result, Options{})
+
+		if item.expectError {
+			if err == nil {
+				t.Errorf("%s: expecting an error", item.description)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", item.description, err)
+			continue
+		}
+
+		if fmt.Sprintf("%T", printer) != fmt.Sprintf("%T", item.expected) {
+			t.Errorf("%s: expected %T and got %T", item.description, item.expected, printer)
+		}
+	}
+}
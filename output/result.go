@@ -0,0 +1,215 @@
+package output
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/registrobr/rdap/protocol"
+)
+
+// Result stores the outcome of a single query, with the object returned by
+// the RDAP server or the error that prevented it
+type Result struct {
+	Query      string
+	ObjectType string
+	Server     string
+	Status     int
+	Elapsed    time.Duration
+	Object     any
+	Err        error
+
+	// Redacted lists the members of the object removed or changed by the
+	// server, taken from the response as they aren't part of Object
+	Redacted []Redaction
+
+	// FromCache is set when the response came from the local cache, that was
+	// retrieved from the server at FetchedAt
+	FromCache bool
+	FetchedAt time.Time
+
+	// Access is the access the response was served at, when the request
+	// was authenticated or the server reported its access level
+	Access *Access
+
+	// Bootstrap is the bootstrap entry that selected the server, when the
+	// client has matched it
+	Bootstrap *BootstrapMatch
+
+	// Failover lists the servers that failed before the one that answered,
+	// in the order they were tried
+	Failover []FailedServer
+
+	// Referrals are the results of the objects referred by the object, like
+	// the domain object of the registrar, in the order they were followed.
+	// ReferredBy is the server that referred the object of a referral
+	Referrals  []*Result
+	ReferredBy string
+}
+
+// FailedServer is a server that couldn't answer the query, that moved to the
+// next server listed for the object
+type FailedServer struct {
+	Server string `json:"server"`
+	Error  string `json:"error"`
+}
+
+// Access tells the authentication scheme of the credentials sent with the
+// request, “basic” or “bearer”, and the access level reported by the server
+type Access struct {
+	Authentication string `json:"authentication,omitempty"`
+	Level          string `json:"level,omitempty"`
+}
+
+// BootstrapMatch is the entry of a bootstrap service registry that lists the
+// RDAP servers of the queried object
+type BootstrapMatch struct {
+	Registry string `json:"registry"`
+	Entry    string `json:"entry"`
+}
+
+// Age returns how old the cached response is
+func (r *Result) Age() time.Duration {
+	if !r.FromCache || r.FetchedAt.IsZero() {
+		return 0
+	}
+
+	return time.Since(r.FetchedAt).Truncate(time.Second)
+}
+
+type jsonLine struct {
+	Query      string          `json:"query"`
+	ObjectType string          `json:"objectType,omitempty"`
+	Server     string          `json:"server,omitempty"`
+	Status     int             `json:"status,omitempty"`
+	ElapsedMS  int64           `json:"elapsedMs"`
+	Object     any             `json:"object,omitempty"`
+	FromCache  bool            `json:"fromCache,omitempty"`
+	FetchedAt  *time.Time      `json:"fetchedAt,omitempty"`
+	AgeSeconds int64           `json:"ageSeconds,omitempty"`
+	Redacted   []Redaction     `json:"redacted,omitempty"`
+	Access     *Access         `json:"access,omitempty"`
+	Bootstrap  *BootstrapMatch `json:"bootstrap,omitempty"`
+	Failover   []FailedServer  `json:"failover,omitempty"`
+	ReferredBy string          `json:"referredBy,omitempty"`
+	Referrals  []*jsonLine     `json:"referrals,omitempty"`
+	Error      *jsonError      `json:"error,omitempty"`
+}
+
+func newJSONLine(r *Result) *jsonLine {
+	line := jsonLine{
+		Query:      r.Query,
+		ObjectType: r.ObjectType,
+		Server:     r.Server,
+		Status:     r.Status,
+		ElapsedMS:  r.Elapsed.Milliseconds(),
+		Object:     r.Object,
+		Redacted:   r.Redacted,
+		Access:     r.Access,
+		Bootstrap:  r.Bootstrap,
+		Failover:   r.Failover,
+		ReferredBy: r.ReferredBy,
+	}
+
+	if r.FromCache {
+		line.FromCache = true
+		line.FetchedAt = &r.FetchedAt
+		line.AgeSeconds = int64(r.Age().Seconds())
+	}
+
+	if r.Err != nil {
+		line.Object = nil
+		line.Redacted = nil
+		line.Error = newJSONError(r.Err)
+	}
+
+	for _, referral := range r.Referrals {
+		line.Referrals = append(line.Referrals, newJSONLine(referral))
+	}
+
+	return &line
+}
+
+type jsonError struct {
+	Message     string   `json:"message"`
+	Code        int      `json:"code,omitempty"`
+	Title       string   `json:"title,omitempty"`
+	Description []string `json:"description,omitempty"`
+}
+
+func newJSONError(err error) *jsonError {
+	e := jsonError{Message: err.Error()}
+
+	var rdapErr protocol.Error
+	if errors.As(err, &rdapErr) {
+		e.Code = rdapErr.ErrorCode
+		e.Title = rdapErr.Title
+		e.Description = rdapErr.Description
+	}
+
+	return &e
+}
+
+// JSONLine prints the result as a compact JSON document in a single line, so
+// a sequence of results can be consumed as JSON Lines
+type JSONLine struct {
+	Result *Result
+}
+
+func (j *JSONLine) Print(wr io.Writer) error {
+	return json.NewEncoder(wr).Encode(newJSONLine(j.Result))
+}
+
+// QueryNotice prints comment lines telling how the query was resolved,
+// before the output of Printer: the server of the response when there are
+// referrals, the bootstrap entry that selected the server, the servers that
+// failed, the access the response was served at and the age of the cached
+// response
+type QueryNotice struct {
+	Printer Printer
+	Result  *Result
+}
+
+func (q *QueryNotice) Print(wr io.Writer) error {
+	var notice strings.Builder
+
+	if q.Result.ReferredBy != "" {
+		fmt.Fprintf(&notice, "%% response from %s, referred by %s\n", q.Result.Server, q.Result.ReferredBy)
+	} else if len(q.Result.Referrals) > 0 {
+		fmt.Fprintf(&notice, "%% response from %s\n", q.Result.Server)
+	}
+
+	if q.Result.Bootstrap != nil {
+		fmt.Fprintf(&notice, "%% bootstrap entry “%s” matched in %s.json\n",
+			q.Result.Bootstrap.Entry, q.Result.Bootstrap.Registry)
+	}
+
+	for _, failed := range q.Result.Failover {
+		fmt.Fprintf(&notice, "%% server %s failed: %s\n", failed.Server, failed.Error)
+	}
+
+	if access := q.Result.Access; access != nil {
+		switch {
+		case access.Authentication != "" && access.Level != "":
+			fmt.Fprintf(&notice, "%% authenticated (%s), access level “%s”\n", access.Authentication, access.Level)
+		case access.Authentication != "":
+			fmt.Fprintf(&notice, "%% authenticated (%s)\n", access.Authentication)
+		default:
+			fmt.Fprintf(&notice, "%% access level “%s”\n", access.Level)
+		}
+	}
+
+	if q.Result.FromCache {
+		fmt.Fprintf(&notice, "%% from cache, retrieved at %s (%s ago)\n",
+			q.Result.FetchedAt.UTC().Format(time.RFC3339), q.Result.Age())
+	}
+
+	if _, err := io.WriteString(wr, notice.String()); err != nil {
+		return err
+	}
+
+	return q.Printer.Print(wr)
+}
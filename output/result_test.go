@@ -0,0 +1,87 @@
+package output
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/registrobr/rdap/protocol"
+)
+
+func TestJSONLinePrint(t *testing.T) {
+	data := []struct {
+		description string
+		result      Result
+		expected    string
+	}{
+		{
+			description: "it should print the object with the query details",
+			result: Result{
+				Query:      "example.br",
+				ObjectType: "domain",
+				Server:     "https://rdap.registro.br/domain/example.br",
+				Status:     200,
+				Elapsed:    1500 * time.Millisecond,
+				Object: &protocol.Domain{
+					ObjectClassName: "domain",
+					LDHName:         "example.br",
+				},
+			},
+			expected: `{"query":"example.br","objectType":"domain","server":"https://rdap.registro.br/domain/example.br","status":200,"elapsedMs":1500,"object":{"objectClassName":"domain","ldhName":"example.br"}}` + "\n",
+		},
+		{
+			description: "it should print the access of the response",
+			result: Result{
+				Query:      "example.br",
+				ObjectType: "domain",
+				Server:     "https://rdap.registro.br/domain/example.br",
+				Status:     200,
+				Object:     &protocol.Domain{ObjectClassName: "domain"},
+				Access:     &Access{Authentication: "bearer", Level: "registrar"},
+			},
+			expected: `{"query":"example.br","objectType":"domain","server":"https://rdap.registro.br/domain/example.br","status":200,"elapsedMs":0,"object":{"objectClassName":"domain"},"access":{"authentication":"bearer","level":"registrar"}}` + "\n",
+		},
+		{
+			description: "it should print a generic error",
+			result: Result{
+				Query:      "example.br",
+				ObjectType: "domain",
+				Server:     "https://rdap.registro.br/domain/example.br",
+				Status:     404,
+				Elapsed:    20 * time.Millisecond,
+				Err:        errors.New("not found"),
+			},
+			expected: `{"query":"example.br","objectType":"domain","server":"https://rdap.registro.br/domain/example.br","status":404,"elapsedMs":20,"error":{"message":"not found"}}` + "\n",
+		},
+		{
+			description: "it should detail an RDAP error",
+			result: Result{
+				Query:      "example.br",
+				ObjectType: "domain",
+				Status:     429,
+				Err: protocol.Error{
+					ErrorCode:   429,
+					Title:       "Too many requests",
+					Description: []string{"Query limit exceeded"},
+				},
+			},
+			expected: `{"query":"example.br","objectType":"domain","status":429,"elapsedMs":0,"error":{"message":"HTTP status code: 429 (Too Many Requests)\nToo many requests:\n  Query limit exceeded","code":429,"title":"Too many requests","description":["Query limit exceeded"]}}` + "\n",
+		},
+	}
+
+	for _, item := range data {
+		jsonLine := JSONLine{Result: &item.result}
+
+		var w WriterMock
+		if err := jsonLine.Print(&w); err != nil {
+			t.Fatalf("%s: %s", item.description, err)
+		}
+
+		if string(w.Content) != item.expected {
+			for _, l := range diff(item.expected, string(w.Content)) {
+				t.Log(l)
+			}
+			t.Fatalf("%s: unexpected output", item.description)
+		}
+	}
+}
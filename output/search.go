@@ -0,0 +1,160 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/registrobr/rdap/protocol"
+)
+
+// SearchResults stores the response of an RDAP search query, as described in
+// RFC 9083, section 8. Only the member of the searched object type is filled
+type SearchResults struct {
+	Domains     []protocol.Domain     `json:"domainSearchResults,omitempty"`
+	Entities    []protocol.Entity     `json:"entitySearchResults,omitempty"`
+	Nameservers []protocol.Nameserver `json:"nameserverSearchResults,omitempty"`
+
+	// Notices are decoded as remarks to keep their type, used by the servers
+	// to report truncated result sets
+	Notices []protocol.Remark `json:"notices,omitempty"`
+	Remarks []protocol.Remark `json:"remarks,omitempty"`
+	Lang    string            `json:"lang,omitempty"`
+	protocol.Conformance
+}
+
+func isTruncation(remark protocol.Remark) bool {
+	return strings.HasPrefix(remark.Type, "result set truncated")
+}
+
+// Truncations returns the reasons reported by the server for not returning
+// the whole result set
+func (s *SearchResults) Truncations() []protocol.Remark {
+	var truncations []protocol.Remark
+
+	for _, remarks := range [][]protocol.Remark{s.Notices, s.Remarks} {
+		for _, remark := range remarks {
+			if isTruncation(remark) {
+				truncations = append(truncations, remark)
+			}
+		}
+	}
+
+	return truncations
+}
+
+// noticeSection returns the notices and remarks of the result set, except
+// the truncations that are printed apart, or nil when there's none
+func (s *SearchResults) noticeSection() *noticeSection {
+	var section noticeSection
+
+	for _, notice := range s.Notices {
+		if !isTruncation(notice) {
+			section.Notices = append(section.Notices, newNoticeInfo(notice.Title, notice.Description, notice.Links))
+		}
+	}
+
+	for _, remark := range s.Remarks {
+		if !isTruncation(remark) {
+			section.Remarks = append(section.Remarks, newNoticeInfo(remark.Title, remark.Description, remark.Links))
+		}
+	}
+
+	if len(section.Notices) == 0 && len(section.Remarks) == 0 {
+		return nil
+	}
+
+	return &section
+}
+
+// printMember prints an object of a search result set apart from the others,
+// with a blank line before and after it, whatever the blank lines of its
+// template
+func printMember(wr io.Writer, p Printer) error {
+	var buf bytes.Buffer
+	if err := p.Print(&buf); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintf(wr, "\n%s\n\n", strings.Trim(buf.String(), "\n"))
+	return err
+}
+
+// Search prints each object of a search result set using the printer of its
+// object type, followed by the notices and remarks of the result set and the
+// notices of truncated results
+type Search struct {
+	Results *SearchResults
+
+	// Templates replaces the default templates of the object types, like in
+	// Options
+	Templates map[string]string
+
+	// HideNotices leaves the notices, remarks and links of each object and of
+	// the result set out, except the notices of truncated results
+	HideNotices bool
+}
+
+func (s *Search) Print(wr io.Writer) error {
+	for i := range s.Results.Domains {
+		domain := Domain{
+			Domain:      &s.Results.Domains[i],
+			Template:    s.Templates["domain"],
+			HideNotices: s.HideNotices,
+		}
+
+		if err := printMember(wr, &domain); err != nil {
+			return err
+		}
+	}
+
+	for i := range s.Results.Entities {
+		entity := Entity{
+			Entity:      &s.Results.Entities[i],
+			Template:    s.Templates["entity"],
+			HideNotices: s.HideNotices,
+		}
+
+		if err := printMember(wr, &entity); err != nil {
+			return err
+		}
+	}
+
+	for i := range s.Results.Nameservers {
+		nameserver := Nameserver{
+			Nameserver:  &s.Results.Nameservers[i],
+			Template:    s.Templates["nameserver"],
+			HideNotices: s.HideNotices,
+		}
+
+		if err := printMember(wr, &nameserver); err != nil {
+			return err
+		}
+	}
+
+	if section := s.Results.noticeSection(); section != nil && !s.HideNotices {
+		t, err := newTemplate("search notices", "\n"+noticesTmplCall)
+		if err != nil {
+			return err
+		}
+
+		if err := t.Execute(wr, struct{ Notices *noticeSection }{section}); err != nil {
+			return err
+		}
+	}
+
+	for _, truncation := range s.Results.Truncations() {
+		if _, err := fmt.Fprintf(wr, "\n%% %s\n", truncation.Type); err != nil {
+			return err
+		}
+
+		for _, description := range truncation.Description {
+			if _, err := fmt.Fprintf(wr, "%% %s\n", description); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
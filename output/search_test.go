@@ -0,0 +1,197 @@
+package output
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestSearchPrint(t *testing.T) {
+	response := `{
+  "rdapConformance": ["rdap_level_0"],
+  "domainSearchResults": [
+    {"objectClassName": "domain", "ldhName": "example1.br", "status": ["active"]},
+    {"objectClassName": "domain", "ldhName": "example2.br"}
+  ],
+  "notices": [
+    {"title": "Terms of use", "description": ["Don't abuse"]},
+    {
+      "title": "Search policy",
+      "type": "result set truncated due to excessive load",
+      "description": ["Only the first 2 results were returned"]
+    }
+  ]
+}`
+
+	var results SearchResults
+	if err := json.Unmarshal([]byte(response), &results); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `
+domain:   example1.br
+status:   active
+
+
+domain:   example2.br
+
+
+notice:   Terms of use
+notice:   Don't abuse
+
+
+% result set truncated due to excessive load
+% Only the first 2 results were returned
+`
+
+	search := Search{Results: &results}
+
+	var w WriterMock
+	if err := search.Print(&w); err != nil {
+		t.Fatal(err)
+	}
+
+	if string(w.Content) != expected {
+		for _, l := range diff(expected, string(w.Content)) {
+			t.Log(l)
+		}
+		t.Fatal("error")
+	}
+
+	// the truncations are still printed without the notices
+	search.HideNotices = true
+
+	w = WriterMock{}
+	if err := search.Print(&w); err != nil {
+		t.Fatal(err)
+	}
+
+	if content := string(w.Content); strings.Contains(content, "Terms of use") || !strings.Contains(content, "% result set truncated") {
+		t.Errorf("unexpected output without notices:\n%s", content)
+	}
+}
+
+func TestNameserverSearchPrint(t *testing.T) {
+	response := `{
+  "nameserverSearchResults": [
+    {
+      "objectClassName": "nameserver",
+      "ldhName": "a.dns.br",
+      "ipAddresses": {"v4": ["200.160.0.10"], "v6": ["2001:12ff::10"]}
+    }
+  ]
+}`
+
+	var results SearchResults
+	if err := json.Unmarshal([]byte(response), &results); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `
+nserver:  a.dns.br
+ipv4:     200.160.0.10
+ipv6:     2001:12ff::10
+
+`
+
+	search := Search{Results: &results}
+
+	var w WriterMock
+	if err := search.Print(&w); err != nil {
+		t.Fatal(err)
+	}
+
+	if string(w.Content) != expected {
+		for _, l := range diff(expected, string(w.Content)) {
+			t.Log(l)
+		}
+		t.Fatal("error")
+	}
+}
+
+func TestSearchPrintSeparator(t *testing.T) {
+	response := `{
+  "domainSearchResults": [
+    {"objectClassName": "domain", "ldhName": "example1.br"},
+    {"objectClassName": "domain", "ldhName": "example2.br"}
+  ],
+  "entitySearchResults": [
+    {"objectClassName": "entity", "handle": "XXXX"}
+  ],
+  "nameserverSearchResults": [
+    {"objectClassName": "nameserver", "ldhName": "a.dns.br"},
+    {"objectClassName": "nameserver", "ldhName": "b.dns.br"}
+  ]
+}`
+
+	var results SearchResults
+	if err := json.Unmarshal([]byte(response), &results); err != nil {
+		t.Fatal(err)
+	}
+
+	data := []struct {
+		description string
+		templates   map[string]string
+		expected    string
+	}{
+		{
+			description: "it should separate the objects of the default templates",
+			expected: `
+domain:   example1.br
+
+
+domain:   example2.br
+
+
+handle:   XXXX
+
+
+nserver:  a.dns.br
+
+
+nserver:  b.dns.br
+
+`,
+		},
+		{
+			description: "it should separate the objects of templates without blank lines",
+			templates: map[string]string{
+				"domain":     "domain {{.Domain.LDHName}}",
+				"entity":     "entity {{.Entity.Handle}}",
+				"nameserver": "nameserver {{.Nameserver.LDHName}}",
+			},
+			expected: `
+domain example1.br
+
+
+domain example2.br
+
+
+entity XXXX
+
+
+nameserver a.dns.br
+
+
+nameserver b.dns.br
+
+`,
+		},
+	}
+
+	for _, item := range data {
+		search := Search{Results: &results, Templates: item.templates}
+
+		var w WriterMock
+		if err := search.Print(&w); err != nil {
+			t.Fatalf("%s: %s", item.description, err)
+		}
+
+		if string(w.Content) != item.expected {
+			for _, l := range diff(item.expected, string(w.Content)) {
+				t.Log(l)
+			}
+			t.Errorf("%s: unexpected output", item.description)
+		}
+	}
+}
@@ -0,0 +1,356 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/registrobr/rdap/protocol"
+)
+
+// SummaryVersion identifies the field layout of Summary. New fields may be
+// added without changing it, but removing a field or changing its meaning
+// always creates a new version
+const SummaryVersion = "1"
+
+// Summary is a normalized view of an RDAP object. It carries the same derived
+// data shown by the default printers (dates taken from events, DS records, IP
+// networks from links and deduplicated contacts with merged roles), so other
+// tools don't need to parse events or vCards again. Members that don't apply
+// to the object type are omitted
+type Summary struct {
+	Version         string   `json:"version"`
+	ObjectClassName string   `json:"objectClassName"`
+	Handle          string   `json:"handle,omitempty"`
+	Name            string   `json:"name,omitempty"`
+	Status          []string `json:"status,omitempty"`
+
+	CreatedAt *time.Time `json:"createdAt,omitempty"`
+	UpdatedAt *time.Time `json:"updatedAt,omitempty"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+
+	// domain
+	Nameservers []string    `json:"nameservers,omitempty"`
+	DS          []DSSummary `json:"ds,omitempty"`
+
+	// nameserver
+	IPv4Addresses []string `json:"ipv4Addresses,omitempty"`
+	IPv6Addresses []string `json:"ipv6Addresses,omitempty"`
+
+	// autnum
+	StartAutnum uint32   `json:"startAutnum,omitempty"`
+	EndAutnum   uint32   `json:"endAutnum,omitempty"`
+	IPNetworks  []string `json:"ipNetworks,omitempty"`
+
+	// ip network
+	StartAddress string `json:"startAddress,omitempty"`
+	EndAddress   string `json:"endAddress,omitempty"`
+	IPVersion    string `json:"ipVersion,omitempty"`
+	ParentHandle string `json:"parentHandle,omitempty"`
+	Autnum       uint32 `json:"autnum,omitempty"`
+
+	// autnum and ip network
+	Type    string `json:"type,omitempty"`
+	Country string `json:"country,omitempty"`
+
+	ContactInfo []ContactSummary `json:"contactInfo"`
+
+	// Redacted lists the redactions that don't belong to a contact
+	Redacted []RedactedField `json:"redacted,omitempty"`
+
+	// Referrals lists the servers of the referred objects merged into the
+	// summary
+	Referrals []string `json:"referrals,omitempty"`
+}
+
+// DSSummary is a delegation signer record of a domain
+type DSSummary struct {
+	KeyTag     int        `json:"keyTag"`
+	Algorithm  int        `json:"algorithm"`
+	Digest     string     `json:"digest"`
+	DigestType int        `json:"digestType"`
+	CreatedAt  *time.Time `json:"createdAt,omitempty"`
+}
+
+// ContactSummary is an entity related to the object, with all the roles it
+// plays in it
+type ContactSummary struct {
+	Handle    string     `json:"handle"`
+	IDs       []string   `json:"ids,omitempty"`
+	Roles     []string   `json:"roles,omitempty"`
+	Persons   []string   `json:"persons,omitempty"`
+	Emails    []string   `json:"emails,omitempty"`
+	Addresses []string   `json:"addresses,omitempty"`
+	Phones    []string   `json:"phones,omitempty"`
+	CreatedAt *time.Time `json:"createdAt,omitempty"`
+	UpdatedAt *time.Time `json:"updatedAt,omitempty"`
+
+	// Redacted lists the fields of the contact hidden by the server
+	Redacted []RedactedField `json:"redacted,omitempty"`
+}
+
+func summaryDate(date protocol.EventDate) *time.Time {
+	if date.IsZero() {
+		return nil
+	}
+
+	t := date.UTC()
+	return &t
+}
+
+func summaryContacts(contacts []contactInfo) []ContactSummary {
+	summaries := make([]ContactSummary, 0, len(contacts))
+
+	for _, c := range contacts {
+		summaries = append(summaries, ContactSummary{
+			Handle:    c.Handle,
+			IDs:       c.Ids,
+			Roles:     c.Roles,
+			Persons:   c.Persons,
+			Emails:    c.Emails,
+			Addresses: c.Addresses,
+			Phones:    c.Phones,
+			CreatedAt: summaryDate(c.CreatedAt),
+			UpdatedAt: summaryDate(c.UpdatedAt),
+			Redacted:  c.Redactions,
+		})
+	}
+
+	return summaries
+}
+
+func statusList(status []protocol.Status) []string {
+	var list []string
+	for _, s := range status {
+		list = append(list, string(s))
+	}
+
+	return list
+}
+
+// NewSummary builds the normalized view of a domain, autnum, IP network,
+// entity or nameserver returned by the RDAP client, with the redactions
+// reported in the response
+func NewSummary(object any, redacted []Redaction) (*Summary, error) {
+	summary := Summary{Version: SummaryVersion}
+
+	switch object := object.(type) {
+	case *protocol.Domain:
+		d := Domain{Domain: object, Redacted: redacted}
+		d.prepare()
+
+		summary.ObjectClassName = object.ObjectClassName
+		summary.Handle = object.Handle
+		summary.Name = object.LDHName
+		summary.Status = statusList(object.Status)
+		summary.CreatedAt = summaryDate(d.CreatedAt)
+		summary.UpdatedAt = summaryDate(d.UpdatedAt)
+		summary.ExpiresAt = summaryDate(d.ExpiresAt)
+		summary.ContactInfo = summaryContacts(d.ContactsInfos)
+		summary.Redacted = d.Redactions
+
+		for _, ns := range object.Nameservers {
+			summary.Nameservers = append(summary.Nameservers, ns.LDHName)
+		}
+
+		for _, ds := range d.DS {
+			summary.DS = append(summary.DS, DSSummary{
+				KeyTag:     ds.KeyTag,
+				Algorithm:  ds.Algorithm,
+				Digest:     ds.Digest,
+				DigestType: ds.DigestType,
+				CreatedAt:  summaryDate(ds.CreatedAt),
+			})
+		}
+
+	case *protocol.AS:
+		a := AS{AS: object, Redacted: redacted}
+		a.prepare()
+
+		summary.ObjectClassName = object.ObjectClassName
+		summary.Handle = object.Handle
+		summary.Name = object.Name
+		summary.StartAutnum = object.StartAutnum
+		summary.EndAutnum = object.EndAutnum
+		summary.Type = object.Type
+		summary.Country = object.Country
+		summary.IPNetworks = a.IPNetworks
+		summary.CreatedAt = summaryDate(a.CreatedAt)
+		summary.UpdatedAt = summaryDate(a.UpdatedAt)
+		summary.ContactInfo = summaryContacts(a.ContactsInfos)
+		summary.Redacted = a.Redactions
+
+	case *protocol.IPNetwork:
+		i := IPNetwork{IPNetwork: object, Redacted: redacted}
+		i.prepare()
+
+		summary.ObjectClassName = object.ObjectClassName
+		summary.Handle = object.Handle
+		summary.Name = object.Name
+		summary.Status = object.Status
+		summary.StartAddress = object.StartAddress
+		summary.EndAddress = object.EndAddress
+		summary.IPVersion = object.IPVersion
+		summary.ParentHandle = object.ParentHandle
+		summary.Autnum = object.Autnum
+		summary.Type = object.Type
+		summary.Country = object.Country
+		summary.CreatedAt = summaryDate(i.CreatedAt)
+		summary.UpdatedAt = summaryDate(i.UpdatedAt)
+		summary.ContactInfo = summaryContacts(i.ContactsInfos)
+		summary.Redacted = i.Redactions
+
+	case *protocol.Entity:
+		e := Entity{Entity: object, Redacted: redacted}
+		e.prepare()
+
+		summary.ObjectClassName = object.ObjectClassName
+		summary.Handle = object.Handle
+		summary.CreatedAt = summaryDate(e.CreatedAt)
+		summary.UpdatedAt = summaryDate(e.UpdatedAt)
+		summary.ContactInfo = summaryContacts(e.ContactsInfos)
+		summary.Redacted = e.Redactions
+
+	case *protocol.Nameserver:
+		n := Nameserver{Nameserver: object, Redacted: redacted}
+		n.prepare()
+
+		summary.ObjectClassName = object.ObjectClassName
+		summary.Handle = object.Handle
+		summary.Name = object.LDHName
+		summary.Status = statusList(object.Status)
+		summary.CreatedAt = summaryDate(n.CreatedAt)
+		summary.UpdatedAt = summaryDate(n.UpdatedAt)
+		summary.ContactInfo = summaryContacts(n.ContactsInfos)
+		summary.Redacted = n.Redactions
+
+		if object.IPAddresses != nil {
+			summary.IPv4Addresses = object.IPAddresses.V4
+			summary.IPv6Addresses = object.IPAddresses.V6
+		}
+
+	default:
+		return nil, fmt.Errorf("unsupported object type %T", object)
+	}
+
+	return &summary, nil
+}
+
+// merge completes the summary with the summary of a referred object, like
+// the domain object of the registrar, that usually has the full contact data.
+// The members missing from the summary are taken from the referred object,
+// and its contacts replace the ones with the same handle
+func (s *Summary) merge(referral *Summary, server string) {
+	if s.Handle == "" {
+		s.Handle = referral.Handle
+	}
+
+	if s.Name == "" {
+		s.Name = referral.Name
+	}
+
+	if len(s.Status) == 0 {
+		s.Status = referral.Status
+	}
+
+	if s.CreatedAt == nil {
+		s.CreatedAt = referral.CreatedAt
+	}
+
+	if s.UpdatedAt == nil {
+		s.UpdatedAt = referral.UpdatedAt
+	}
+
+	if s.ExpiresAt == nil {
+		s.ExpiresAt = referral.ExpiresAt
+	}
+
+	if len(s.Nameservers) == 0 {
+		s.Nameservers = referral.Nameservers
+	}
+
+	if len(s.DS) == 0 {
+		s.DS = referral.DS
+	}
+
+	for _, contact := range referral.ContactInfo {
+		i := slices.IndexFunc(s.ContactInfo, func(c ContactSummary) bool {
+			return c.Handle != "" && c.Handle == contact.Handle
+		})
+
+		if i < 0 {
+			s.ContactInfo = append(s.ContactInfo, contact)
+		} else {
+			s.ContactInfo[i] = contact
+		}
+	}
+
+	s.Redacted = append(s.Redacted, referral.Redacted...)
+	s.Referrals = append(s.Referrals, server)
+}
+
+// JSONSummary prints the normalized view of the object as an indented JSON
+// document, merged with the objects of the successful referrals
+type JSONSummary struct {
+	Object    any
+	Redacted  []Redaction
+	Referrals []*Result
+}
+
+func (j *JSONSummary) Print(wr io.Writer) error {
+	summary, err := NewSummary(j.Object, j.Redacted)
+	if err != nil {
+		return err
+	}
+
+	for _, referral := range j.Referrals {
+		if referral.Err != nil {
+			continue
+		}
+
+		referralSummary, err := NewSummary(referral.Object, referral.Redacted)
+		if err != nil {
+			return err
+		}
+
+		summary.merge(referralSummary, referral.Server)
+	}
+
+	output, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(wr, string(output))
+	return err
+}
+
+// summaryError is the summary of a failed query, with the error in the same
+// format of the jsonl output
+type summaryError struct {
+	Version string     `json:"version"`
+	Query   string     `json:"query"`
+	Error   *jsonError `json:"error"`
+}
+
+// JSONSummaryError prints a failed query as a JSON document, so the output
+// of a batch is still a sequence of JSON documents
+type JSONSummaryError struct {
+	Result *Result
+}
+
+func (j *JSONSummaryError) Print(wr io.Writer) error {
+	output, err := json.MarshalIndent(summaryError{
+		Version: SummaryVersion,
+		Query:   j.Result.Query,
+		Error:   newJSONError(j.Result.Err),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(wr, string(output))
+	return err
+}
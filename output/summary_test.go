@@ -0,0 +1,231 @@
+package output
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/registrobr/rdap/protocol"
+)
+
+func TestJSONSummaryPrint(t *testing.T) {
+	contact := protocol.Entity{
+		ObjectClassName: "entity",
+		Handle:          "XXXX",
+		Roles:           []string{"registrant"},
+		VCardArray: []any{
+			"vcard",
+			[]any{
+				[]any{"version", struct{}{}, "text", "4.0"},
+				[]any{"fn", struct{}{}, "text", "Joe User"},
+				[]any{"email", struct{ Type string }{Type: "work"}, "text", "joe.user@example.com"},
+			},
+		},
+		Events: []protocol.Event{
+			{
+				Action: protocol.EventActionRegistration,
+				Date:   protocol.Date(2015, 03, 01, 12, 00, 00, 00, time.UTC),
+			},
+		},
+	}
+
+	technical := contact
+	technical.Roles = []string{"technical"}
+
+	domain := &protocol.Domain{
+		ObjectClassName: "domain",
+		LDHName:         "example.br",
+		Status:          []protocol.Status{"active"},
+		Entities:        []protocol.Entity{contact, technical},
+		Events: []protocol.Event{
+			{
+				Action: protocol.EventActionRegistration,
+				Date:   protocol.Date(2015, 03, 01, 12, 00, 00, 00, time.UTC),
+			},
+			{
+				Action: protocol.EventActionExpiration,
+				Date:   protocol.Date(2016, 03, 01, 12, 00, 00, 00, time.UTC),
+			},
+		},
+		Nameservers: []protocol.Nameserver{
+			{ObjectClassName: "nameserver", LDHName: "a.dns.br"},
+			{ObjectClassName: "nameserver", LDHName: "b.dns.br"},
+		},
+		SecureDNS: &protocol.SecureDNS{
+			DSData: []protocol.DS{
+				{
+					KeyTag:     12345,
+					Algorithm:  5,
+					Digest:     "0123456789ABCDEF0123456789ABCDEF01234567",
+					DigestType: 1,
+				},
+			},
+		},
+	}
+
+	expected := `{
+  "version": "1",
+  "objectClassName": "domain",
+  "name": "example.br",
+  "status": [
+    "active"
+  ],
+  "createdAt": "2015-03-01T12:00:00Z",
+  "expiresAt": "2016-03-01T12:00:00Z",
+  "nameservers": [
+    "a.dns.br",
+    "b.dns.br"
+  ],
+  "ds": [
+    {
+      "keyTag": 12345,
+      "algorithm": 5,
+      "digest": "0123456789ABCDEF0123456789ABCDEF01234567",
+      "digestType": 1
+    }
+  ],
+  "contactInfo": [
+    {
+      "handle": "XXXX",
+      "roles": [
+        "registrant",
+        "technical"
+      ],
+      "persons": [
+        "Joe User"
+      ],
+      "emails": [
+        "joe.user@example.com"
+      ],
+      "createdAt": "2015-03-01T12:00:00Z"
+    }
+  ]
+}
+`
+
+	summary := JSONSummary{Object: domain}
+
+	var w WriterMock
+	if err := summary.Print(&w); err != nil {
+		t.Fatal(err)
+	}
+
+	if string(w.Content) != expected {
+		for _, l := range diff(expected, string(w.Content)) {
+			t.Log(l)
+		}
+		t.Fatal("error")
+	}
+}
+
+func TestNewSummaryUnsupportedObject(t *testing.T) {
+	if _, err := NewSummary(&SearchResults{}, nil); err == nil {
+		t.Fatal("expecting an error")
+	}
+}
+
+func TestJSONSummaryErrorPrint(t *testing.T) {
+	result := Result{
+		Query: "example.br",
+		Err:   protocol.Error{ErrorCode: 404, Title: "Not Found"},
+	}
+
+	var w WriterMock
+	if err := (&JSONSummaryError{Result: &result}).Print(&w); err != nil {
+		t.Fatal(err)
+	}
+
+	var printed struct {
+		Version string
+		Query   string
+		Error   struct {
+			Code  int
+			Title string
+		}
+	}
+
+	if err := json.Unmarshal(w.Content, &printed); err != nil {
+		t.Fatalf("expected a JSON document and got %s: %v", w.Content, err)
+	}
+
+	if printed.Version != SummaryVersion || printed.Query != "example.br" ||
+		printed.Error.Code != 404 || printed.Error.Title != "Not Found" {
+
+		t.Errorf("unexpected error summary %s", w.Content)
+	}
+}
+
+func TestNewSummaryRedactions(t *testing.T) {
+	entity := &protocol.Entity{
+		ObjectClassName: "entity",
+		Handle:          "XXXX",
+	}
+
+	redacted := []Redaction{
+		{
+			Name:    RedactionText{Description: "Email"},
+			PrePath: "$.vcardArray[1][?(@[0]=='email')]",
+			Reason:  &RedactionText{Type: "Server policy"},
+		},
+		{
+			Name:    RedactionText{Description: "Remarks"},
+			PrePath: "$.remarks",
+		},
+	}
+
+	summary, err := NewSummary(entity, redacted)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedContact := []RedactedField{
+		{Property: "email", Label: "e-mail", Name: "Email", Reason: "Server policy", Method: RedactionRemoval},
+	}
+	if !reflect.DeepEqual(summary.ContactInfo[0].Redacted, expectedContact) {
+		t.Errorf("expected contact redactions %+v and got %+v", expectedContact, summary.ContactInfo[0].Redacted)
+	}
+
+	expected := []RedactedField{{Name: "Remarks", Method: RedactionRemoval}}
+	if !reflect.DeepEqual(summary.Redacted, expected) {
+		t.Errorf("expected redactions %+v and got %+v", expected, summary.Redacted)
+	}
+}
+
+func TestSummaryMerge(t *testing.T) {
+	created := time.Date(2015, 03, 01, 12, 00, 00, 00, time.UTC)
+
+	summary := Summary{
+		Name:        "example.com",
+		Nameservers: []string{"a.iana-servers.net"},
+		ContactInfo: []ContactSummary{
+			{Handle: "XXXX", Roles: []string{"registrant"}, Redacted: []RedactedField{{Name: "Registrant Email"}}},
+			{Handle: "YYYY", Roles: []string{"registrar"}},
+		},
+	}
+
+	summary.merge(&Summary{
+		Name:      "EXAMPLE.COM",
+		CreatedAt: &created,
+		ContactInfo: []ContactSummary{
+			{Handle: "XXXX", Roles: []string{"registrant"}, Emails: []string{"joe.user@example.com"}},
+			{Handle: "ZZZZ", Roles: []string{"technical"}},
+		},
+	}, "https://rdap.registrar.example/domain/example.com")
+
+	expected := Summary{
+		Name:        "example.com",
+		CreatedAt:   &created,
+		Nameservers: []string{"a.iana-servers.net"},
+		ContactInfo: []ContactSummary{
+			{Handle: "XXXX", Roles: []string{"registrant"}, Emails: []string{"joe.user@example.com"}},
+			{Handle: "YYYY", Roles: []string{"registrar"}},
+			{Handle: "ZZZZ", Roles: []string{"technical"}},
+		},
+		Referrals: []string{"https://rdap.registrar.example/domain/example.com"},
+	}
+
+	if !reflect.DeepEqual(summary, expected) {
+		t.Errorf("expected %+v and got %+v", expected, summary)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/urfave/cli"
+)
+
+// proxyRule sends the requests to the hosts matching a pattern through a
+// proxy
+type proxyRule struct {
+	pattern string
+	proxy   *url.URL
+}
+
+// parseProxies reads the proxies in the format “[pattern=]URL”, where a
+// missing pattern matches any host. The URL scheme is “http”, “https” or
+// “socks5”, and the URL may have the credentials of the proxy
+func parseProxies(proxies []string) ([]proxyRule, error) {
+	var rules []proxyRule
+
+	for _, proxy := range proxies {
+		rule := proxyRule{pattern: "*"}
+
+		value := proxy
+		if pos := strings.Index(proxy, "://"); pos != -1 {
+			if eq := strings.LastIndex(proxy[:pos], "="); eq != -1 {
+				rule.pattern = strings.ToLower(proxy[:eq])
+				value = proxy[eq+1:]
+			}
+		}
+
+		if _, err := path.Match(rule.pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid host pattern in “%s”: %w", proxy, err)
+		}
+
+		var err error
+		rule.proxy, err = url.Parse(value)
+		if err != nil || rule.proxy.Host == "" {
+			return nil, fmt.Errorf("invalid proxy “%s”, expected “[pattern=]scheme://host:port”", proxy)
+		}
+
+		switch rule.proxy.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return nil, fmt.Errorf("invalid proxy scheme in “%s”, expected “http”, “https” or “socks5”", proxy)
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+// newProxy returns the proxy function of the HTTP transports. The hosts
+// matching a no-proxy pattern are reached directly, the others through the
+// proxy of the first matching rule or, when there's none, through the proxy
+// of the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables
+func newProxy(ctx *cli.Context) (func(*http.Request) (*url.URL, error), error) {
+	rules, err := parseProxies(ctx.StringSlice("proxy"))
+	if err != nil {
+		return nil, err
+	}
+
+	var noProxy []string
+	for _, pattern := range ctx.StringSlice("no-proxy") {
+		pattern = strings.ToLower(pattern)
+
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid no-proxy host pattern “%s”: %w", pattern, err)
+		}
+
+		noProxy = append(noProxy, pattern)
+	}
+
+	return func(req *http.Request) (*url.URL, error) {
+		host := strings.ToLower(req.URL.Hostname())
+
+		for _, pattern := range noProxy {
+			if ok, _ := path.Match(pattern, host); ok {
+				return nil, nil
+			}
+		}
+
+		proxy, err := http.ProxyFromEnvironment(req)
+		for _, rule := range rules {
+			if ok, _ := path.Match(rule.pattern, host); ok {
+				proxy, err = rule.proxy, nil
+				break
+			}
+		}
+
+		if proxy != nil {
+			tracef(req, "through the proxy %s", proxy.Redacted())
+		}
+
+		return proxy, err
+	}, nil
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// hostRule associates a limit with the hosts matching a pattern, like
+// “rdap.registro.br”, “*.arin.net” or “*”
+type hostRule struct {
+	pattern string
+	value   int
+	per     time.Duration
+}
+
+// parseHostRules reads the rules in the format “[pattern=]value”, where a
+// missing pattern matches any host. When rates is set the value may also be a
+// rate as “value/s”, “value/m” or “value/h”
+func parseHostRules(rules []string, rates bool) ([]hostRule, error) {
+	var parsed []hostRule
+
+	for _, rule := range rules {
+		r := hostRule{pattern: "*", per: time.Second}
+
+		value := rule
+		if pos := strings.LastIndex(rule, "="); pos != -1 {
+			r.pattern = strings.ToLower(rule[:pos])
+			value = rule[pos+1:]
+		}
+
+		if _, err := path.Match(r.pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid host pattern in “%s”: %w", rule, err)
+		}
+
+		if rates {
+			if number, unit, found := strings.Cut(value, "/"); found {
+				switch unit {
+				case "s":
+					r.per = time.Second
+				case "m":
+					r.per = time.Minute
+				case "h":
+					r.per = time.Hour
+				default:
+					return nil, fmt.Errorf("invalid rate unit in “%s”", rule)
+				}
+
+				value = number
+			}
+		}
+
+		var err error
+		if r.value, err = strconv.Atoi(value); err != nil || r.value <= 0 {
+			return nil, fmt.Errorf("invalid value in “%s”", rule)
+		}
+
+		parsed = append(parsed, r)
+	}
+
+	return parsed, nil
+}
+
+// match returns the first rule whose pattern matches the host
+func match(rules []hostRule, host string) (hostRule, bool) {
+	for _, rule := range rules {
+		if ok, _ := path.Match(rule.pattern, host); ok {
+			return rule, true
+		}
+	}
+
+	return hostRule{}, false
+}
+
+// hostLimiter spaces the requests sent to a host and caps how many of them
+// run at the same time
+type hostLimiter struct {
+	mu       sync.Mutex
+	next     time.Time
+	interval time.Duration
+
+	// slots is nil when the concurrency isn't limited
+	slots chan struct{}
+}
+
+// rateLimiter keeps a hostLimiter for each host, built from the first rate
+// and concurrency rules that match it. It is shared by the HTTP clients, so
+// the limits cover both the bootstrap and the RDAP requests
+type rateLimiter struct {
+	rates       []hostRule
+	concurrency []hostRule
+
+	mu    sync.Mutex
+	hosts map[string]*hostLimiter
+}
+
+func newRateLimiter(rates, concurrency []hostRule) *rateLimiter {
+	return &rateLimiter{
+		rates:       rates,
+		concurrency: concurrency,
+		hosts:       make(map[string]*hostLimiter),
+	}
+}
+
+func (r *rateLimiter) host(host string) *hostLimiter {
+	host = strings.ToLower(host)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if limiter, ok := r.hosts[host]; ok {
+		return limiter
+	}
+
+	limiter := new(hostLimiter)
+
+	if rule, ok := match(r.rates, host); ok {
+		limiter.interval = rule.per / time.Duration(rule.value)
+	}
+
+	if rule, ok := match(r.concurrency, host); ok {
+		limiter.slots = make(chan struct{}, rule.value)
+	}
+
+	r.hosts[host] = limiter
+	return limiter
+}
+
+// wait blocks until the request can be sent. The returned function releases
+// the concurrency slot taken by the request
+func (h *hostLimiter) wait(req *http.Request) (func(), error) {
+	release := func() {}
+
+	if h.slots != nil {
+		select {
+		case h.slots <- struct{}{}:
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+
+		release = func() { <-h.slots }
+	}
+
+	if h.interval == 0 {
+		return release, nil
+	}
+
+	h.mu.Lock()
+	now := time.Now()
+	slot := h.next
+	if slot.Before(now) {
+		slot = now
+	}
+	h.next = slot.Add(h.interval)
+	h.mu.Unlock()
+
+	timer := time.NewTimer(time.Until(slot))
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return release, nil
+	case <-req.Context().Done():
+		release()
+		return nil, req.Context().Err()
+	}
+}
+
+// rateLimitTransport is a RoundTripper decorator that applies the limits of
+// the request host. The concurrency slot is released when the response body
+// is read to the end or closed, as the body is still downloaded from the
+// server until then, so every response must be read or closed, including the
+// ones dropped by the cache (see cacheStamp)
+type rateLimitTransport struct {
+	transport http.RoundTripper
+	limiter   *rateLimiter
+}
+
+func (r *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	release, err := r.limiter.host(req.URL.Hostname()).wait(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := r.transport.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+
+	resp.Body = &releaseBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// releaseBody is a response body that releases the concurrency slot of the
+// request once
+type releaseBody struct {
+	io.ReadCloser
+
+	once    sync.Once
+	release func()
+}
+
+func (b *releaseBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil {
+		b.once.Do(b.release)
+	}
+
+	return n, err
+}
+
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
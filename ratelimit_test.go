@@ -0,0 +1,213 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gregjones/httpcache"
+)
+
+func TestParseHostRules(t *testing.T) {
+	data := []struct {
+		description   string
+		rules         []string
+		rates         bool
+		expected      []hostRule
+		expectedError bool
+	}{
+		{
+			description: "it should parse rates with patterns and units",
+			rules:       []string{"*.registro.br=2/s", "rdap.arin.net=30/m", "10"},
+			rates:       true,
+			expected: []hostRule{
+				{pattern: "*.registro.br", value: 2, per: time.Second},
+				{pattern: "rdap.arin.net", value: 30, per: time.Minute},
+				{pattern: "*", value: 10, per: time.Second},
+			},
+		},
+		{
+			description:   "it should not accept rates for concurrency",
+			rules:         []string{"rdap.arin.net=2/s"},
+			expectedError: true,
+		},
+		{
+			description:   "it should not accept unknown units",
+			rules:         []string{"2/d"},
+			rates:         true,
+			expectedError: true,
+		},
+		{
+			description:   "it should not accept invalid patterns",
+			rules:         []string{"[=2"},
+			expectedError: true,
+		},
+		{
+			description:   "it should not accept zero",
+			rules:         []string{"*=0"},
+			expectedError: true,
+		},
+	}
+
+	for _, item := range data {
+		rules, err := parseHostRules(item.rules, item.rates)
+		if item.expectedError {
+			if err == nil {
+				t.Errorf("%s: expected an error", item.description)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", item.description, err)
+		} else if !reflect.DeepEqual(rules, item.expected) {
+			t.Errorf("%s: expected %v and got %v", item.description, item.expected, rules)
+		}
+	}
+}
+
+func TestRateLimitTransport(t *testing.T) {
+	var running, maxRunning int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+
+		for {
+			max := atomic.LoadInt32(&maxRunning)
+			if current <= max || atomic.CompareAndSwapInt32(&maxRunning, max, current) {
+				break
+			}
+		}
+
+		time.Sleep(20 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	rates := []hostRule{{pattern: "127.0.0.*", value: 50, per: time.Second}}
+	concurrency := []hostRule{{pattern: "*", value: 2}}
+
+	client := http.Client{
+		Transport: &rateLimitTransport{
+			transport: http.DefaultTransport,
+			limiter:   newRateLimiter(rates, concurrency),
+		},
+	}
+
+	start := time.Now()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			resp, err := client.Get(server.URL)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			resp.Body.Close()
+		}()
+	}
+	wg.Wait()
+
+	if maxRunning > 2 {
+		t.Errorf("expected at most 2 concurrent requests and got %d", maxRunning)
+	}
+
+	// six requests at 50 per second are spaced by 20ms
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("expected the requests to be spaced and they took %s", elapsed)
+	}
+}
+
+func TestRateLimitTransportBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	client := http.Client{
+		Transport: &rateLimitTransport{
+			transport: http.DefaultTransport,
+			limiter:   newRateLimiter(nil, []hostRule{{pattern: "*", value: 1}}),
+		},
+	}
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		resp.Body.Close()
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("expected the second request to wait for the body of the first one")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	resp.Body.Close()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("expected the second request after the body of the first one was closed")
+	}
+}
+
+func TestRateLimitTransportRevalidation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("ETag", `"v1"`)
+
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	transport := httpcache.NewMemoryCacheTransport()
+	transport.Transport = &cacheStamp{
+		transport: &rateLimitTransport{
+			transport: http.DefaultTransport,
+			limiter:   newRateLimiter(nil, []hostRule{{pattern: "*", value: 1}}),
+		},
+	}
+
+	client := http.Client{Transport: transport, Timeout: time.Second}
+
+	// every revalidation must give the concurrency slot back
+	for i := 0; i < 3; i++ {
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("request %d: unexpected error %v", i+1, err)
+		}
+
+		io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if i > 0 && !fromCache(resp) {
+			t.Errorf("request %d: expected a revalidated response from the cache", i+1)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/registrobr/rdap"
+	"github.com/registrobr/rdap-client/output"
+	"github.com/registrobr/rdap/protocol"
+)
+
+// recorder is a Fetcher decorator that keeps in the query result the object
+// type that was resolved, the details of the server that answered, and the
+// redactions and the access level of the response, that the protocol package
+// doesn't decode
+type recorder struct {
+	fetcher rdap.Fetcher
+	result  *output.Result
+}
+
+func (r *recorder) Fetch(uris []string, queryType rdap.QueryType, queryValue string, header http.Header, queryString url.Values) (*http.Response, error) {
+	r.result.ObjectType = string(queryType)
+
+	resp, err := r.fetcher.Fetch(uris, queryType, queryValue, header, queryString)
+	if resp != nil {
+		r.result.Status = resp.StatusCode
+
+		if resp.Request != nil {
+			r.result.Server = resp.Request.URL.String()
+		}
+
+		recordCache(r.result, resp)
+		recordAccess(r.result, resp)
+
+		if resp.StatusCode == http.StatusOK {
+			if err := r.recordBody(resp); err != nil {
+				return nil, err
+			}
+		}
+
+	} else {
+		recordError(r.result, err)
+	}
+
+	return resp, err
+}
+
+// recordBody reads the “redacted” member and the access level of the
+// response, restoring the body for the RDAP client to decode the object
+func (r *recorder) recordBody(resp *http.Response) error {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	var response struct {
+		Redacted []output.Redaction `json:"redacted"`
+		Notices  []protocol.Notice  `json:"notices"`
+		Remarks  []protocol.Remark  `json:"remarks"`
+	}
+
+	// a response that can't be decoded is reported by the RDAP client
+	if json.Unmarshal(body, &response) != nil {
+		return nil
+	}
+
+	r.result.Redacted = response.Redacted
+
+	if level := accessLevel(response.Notices, response.Remarks); level != "" {
+		if r.result.Access == nil {
+			r.result.Access = &output.Access{}
+		}
+		r.result.Access.Level = level
+	}
+
+	return nil
+}
+
+// accessLevel returns the access level reported by the server, in the first
+// line of the description of a notice or a remark titled “Access Level”, as
+// there's no standard member for it
+func accessLevel(notices []protocol.Notice, remarks []protocol.Remark) string {
+	for _, notice := range notices {
+		if strings.EqualFold(strings.TrimSpace(notice.Title), "access level") && len(notice.Description) > 0 {
+			return strings.TrimSpace(notice.Description[0])
+		}
+	}
+
+	for _, remark := range remarks {
+		if strings.EqualFold(strings.TrimSpace(remark.Title), "access level") && len(remark.Description) > 0 {
+			return strings.TrimSpace(remark.Description[0])
+		}
+	}
+
+	return ""
+}
+
+// recordCache keeps in the query result whether the response came from the
+// cache and when it was retrieved from the server
+func recordCache(result *output.Result, resp *http.Response) {
+	if fromCache(resp) {
+		result.FromCache = true
+		result.FetchedAt = fetchedAt(resp)
+	}
+}
+
+// recordAccess keeps in the query result the authentication scheme of the
+// credentials sent with the request
+func recordAccess(result *output.Result, resp *http.Response) {
+	if scheme := resp.Header.Get(headerAuthentication); scheme != "" {
+		result.Access = &output.Access{Authentication: scheme}
+	}
+}
+
+// recordError keeps in the query result the status of the failed query, when
+// the error tells it
+func recordError(result *output.Result, err error) {
+	var (
+		rdapErr  protocol.Error
+		retryErr *retryError
+	)
+
+	if errors.As(err, &rdapErr) {
+		result.Status = rdapErr.ErrorCode
+	} else if errors.As(err, &retryErr) {
+		result.Status = retryErr.StatusCode
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/registrobr/rdap"
+	"github.com/registrobr/rdap-client/output"
+	"github.com/registrobr/rdap/protocol"
+)
+
+// maxReferrals limits the referrals followed from a query, as each registrar
+// could refer to another one
+const maxReferrals = 3
+
+// domainReferral returns the server and the name of the domain object that
+// a domain refers to with a related link, like the registries of thin gTLDs
+// refer to the domain object of the registrar
+func domainReferral(domain *protocol.Domain) (server, name string, ok bool) {
+	for _, link := range domain.Links {
+		if link.Rel != "related" || strings.Split(link.Type, ";")[0] != "application/rdap+json" {
+			continue
+		}
+
+		u, err := url.Parse(link.Href)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			continue
+		}
+
+		path := strings.TrimRight(u.Path, "/")
+		i := strings.LastIndex(path, "/domain/")
+		if i < 0 || strings.Contains(path[i+len("/domain/"):], "/") {
+			continue
+		}
+
+		name = path[i+len("/domain/"):]
+		u.Path, u.RawPath, u.RawQuery, u.Fragment = path[:i], "", "", ""
+		return u.String(), name, true
+	}
+
+	return "", "", false
+}
+
+// serverBase normalises the base URI of a server, or the URL of a domain
+// query sent to it, to its scheme, host and path prefix, so a server is
+// recognised whatever the name queried or the query string
+func serverBase(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return uri
+	}
+
+	scheme, host := strings.ToLower(u.Scheme), strings.ToLower(u.Host)
+	if (scheme == "http" && strings.HasSuffix(host, ":80")) ||
+		(scheme == "https" && strings.HasSuffix(host, ":443")) {
+		host = host[:strings.LastIndex(host, ":")]
+	}
+
+	path := strings.TrimRight(u.Path, "/")
+	if i := strings.LastIndex(path, "/domain/"); i >= 0 {
+		path = path[:i]
+	}
+
+	return scheme + "://" + host + path
+}
+
+// followReferrals retrieves the domain objects referred by the result, in
+// a chain of at most maxReferrals, stopping when a server was already queried.
+// The results of the referrals are kept in the query result
+func followReferrals(httpClient *http.Client, result *output.Result, queryString url.Values) {
+	visited := map[string]bool{serverBase(result.Server): true}
+	current := result
+
+	for len(result.Referrals) < maxReferrals && current.Err == nil {
+		domain, ok := current.Object.(*protocol.Domain)
+		if !ok {
+			return
+		}
+
+		server, name, ok := domainReferral(domain)
+		if !ok {
+			return
+		}
+
+		if visited[serverBase(server)] {
+			return
+		}
+		visited[serverBase(server)] = true
+
+		referral := &output.Result{
+			Query:      name,
+			ReferredBy: current.Server,
+		}
+
+		client := rdap.Client{
+			URIs: []string{server},
+			Transport: &recorder{
+				fetcher: (&failoverFetcher{httpClient: httpClient}).withResult(referral),
+				result:  referral,
+			},
+		}
+
+		start := time.Now()
+		object, _, err := client.Domain(name, nil, queryString)
+		referral.Elapsed = time.Since(start)
+
+		if err != nil {
+			referral.Err = err
+		} else {
+			referral.Object = object
+		}
+
+		if referral.Server != "" {
+			visited[serverBase(referral.Server)] = true
+		}
+
+		result.Referrals = append(result.Referrals, referral)
+		current = referral
+	}
+}
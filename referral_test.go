@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/registrobr/rdap-client/output"
+	"github.com/registrobr/rdap/protocol"
+)
+
+func TestDomainReferral(t *testing.T) {
+	data := []struct {
+		description    string
+		links          []protocol.Link
+		expectedServer string
+		expectedName   string
+	}{
+		{
+			description: "it should find the domain object of the registrar",
+			links: []protocol.Link{
+				{Rel: "self", Type: "application/rdap+json", Href: "https://rdap.registry.example/domain/example.com"},
+				{Rel: "related", Type: "application/rdap+json", Href: "https://rdap.registrar.example/rdap/domain/example.com"},
+			},
+			expectedServer: "https://rdap.registrar.example/rdap",
+			expectedName:   "example.com",
+		},
+		{
+			description: "it should ignore the links to other documents",
+			links: []protocol.Link{
+				{Rel: "related", Type: "text/html", Href: "https://registrar.example/domain/example.com"},
+				{Rel: "related", Type: "application/rdap+json", Href: "https://rdap.registrar.example/entity/XXXX"},
+			},
+		},
+	}
+
+	for _, item := range data {
+		server, name, _ := domainReferral(&protocol.Domain{Links: item.links})
+
+		if server != item.expectedServer || name != item.expectedName {
+			t.Errorf("%s: expected %s and %s, got %s and %s", item.description,
+				item.expectedServer, item.expectedName, server, name)
+		}
+	}
+}
+
+func TestFollowReferrals(t *testing.T) {
+	var registry, registrar *httptest.Server
+
+	// the registrar refers back to the registry, closing a loop
+	referTo := func(server **httptest.Server) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/rdap+json")
+			fmt.Fprintf(w, `{"objectClassName":"domain","ldhName":"example.com","links":[{"rel":"related","type":"application/rdap+json","href":"%s/domain/example.com"}]}`, (*server).URL)
+		}
+	}
+
+	registry = httptest.NewServer(referTo(&registrar))
+	defer registry.Close()
+
+	registrar = httptest.NewServer(referTo(&registry))
+	defer registrar.Close()
+
+	// the name queried differs from the one of the referral back to the
+	// registry, which must still be recognised as the same server
+	result := &output.Result{
+		Query:  "EXAMPLE.COM",
+		Server: registry.URL + "/domain/EXAMPLE.COM?jscard=1",
+		Object: &protocol.Domain{
+			LDHName: "example.com",
+			Links: []protocol.Link{
+				{Rel: "related", Type: "application/rdap+json", Href: registrar.URL + "/domain/example.com"},
+			},
+		},
+	}
+
+	followReferrals(http.DefaultClient, result, nil)
+
+	if len(result.Referrals) != 1 {
+		t.Fatalf("expected 1 referral and got %d", len(result.Referrals))
+	}
+
+	referral := result.Referrals[0]
+	if referral.Err != nil {
+		t.Fatalf("unexpected error %v", referral.Err)
+	}
+
+	if expected := registrar.URL + "/domain/example.com"; referral.Server != expected {
+		t.Errorf("expected server %s and got %s", expected, referral.Server)
+	}
+
+	if referral.ReferredBy != result.Server {
+		t.Errorf("expected referrer %s and got %s", result.Server, referral.ReferredBy)
+	}
+}
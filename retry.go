@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// retryableStatus lists the HTTP status codes of the transient failures, when
+// the request is sent again
+var retryableStatus = map[int]bool{
+	http.StatusTooManyRequests:    true,
+	http.StatusBadGateway:         true,
+	http.StatusServiceUnavailable: true,
+	http.StatusGatewayTimeout:     true,
+}
+
+// retryError is returned when the server keeps answering with a transient
+// failure after all the attempts
+type retryError struct {
+	StatusCode int
+	Attempts   int
+}
+
+func (e *retryError) Error() string {
+	return fmt.Sprintf("giving up after %d %s: %d %s",
+		e.Attempts, attemptsNoun(e.Attempts), e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+func attemptsNoun(attempts int) string {
+	if attempts == 1 {
+		return "attempt"
+	}
+
+	return "attempts"
+}
+
+// retryTransport is a RoundTripper decorator that sends the request again on
+// network errors and transient failures, waiting an exponential backoff with
+// jitter between the attempts. A Retry-After header sent by the server
+// replaces the backoff, unless it is longer than maxWait
+type retryTransport struct {
+	transport http.RoundTripper
+	retries   int
+	wait      time.Duration
+	maxWait   time.Duration
+}
+
+func (r *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		resp, err := r.transport.RoundTrip(req)
+
+		if err == nil && !retryableStatus[resp.StatusCode] {
+			return resp, nil
+		}
+
+		if req.Context().Err() != nil {
+			// the request was canceled or timed out, so there is no time left
+			// for other attempts
+			return resp, err
+		}
+
+		wait := r.backoff(attempt)
+		if resp != nil {
+			if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+				wait = retryAfter
+			}
+
+			resp.Body.Close()
+		}
+
+		if attempt > r.retries || wait > r.maxWait {
+			if err != nil {
+				return nil, fmt.Errorf("giving up after %d %s: %w", attempt, attemptsNoun(attempt), err)
+			}
+
+			return nil, &retryError{StatusCode: resp.StatusCode, Attempts: attempt}
+		}
+
+		if err != nil {
+			tracef(req, "attempt %d failed, retrying in %s: %v", attempt, wait, err)
+		} else {
+			tracef(req, "attempt %d answered %s, retrying in %s", attempt, resp.Status, wait)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, fmt.Errorf("giving up after %d %s: %w", attempt, attemptsNoun(attempt), req.Context().Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff doubles the wait on each attempt, choosing a random duration in the
+// upper half of it so concurrent clients don't retry at the same time
+func (r *retryTransport) backoff(attempt int) time.Duration {
+	wait := r.wait << (attempt - 1)
+	if wait <= 0 || wait > r.maxWait {
+		wait = r.maxWait
+	}
+
+	half := int64(wait / 2)
+	if half <= 0 {
+		return wait
+	}
+
+	return time.Duration(half + rand.Int63n(half))
+}
+
+// parseRetryAfter reads the Retry-After header, that can be a number of
+// seconds or a date, as described in RFC 9110, section 10.2.3
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+
+	wait := time.Until(date)
+	if wait < 0 {
+		wait = 0
+	}
+
+	return wait, true
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryTransport(t *testing.T) {
+	data := []struct {
+		description      string
+		failures         int
+		status           int
+		retryAfter       string
+		expectedStatus   int
+		expectedAttempts int32
+	}{
+		{
+			description:      "it should retry until the server recovers",
+			failures:         2,
+			status:           http.StatusServiceUnavailable,
+			expectedStatus:   http.StatusOK,
+			expectedAttempts: 3,
+		},
+		{
+			description:      "it should honor the Retry-After of a rate limited request",
+			failures:         1,
+			status:           http.StatusTooManyRequests,
+			retryAfter:       "0",
+			expectedStatus:   http.StatusOK,
+			expectedAttempts: 2,
+		},
+		{
+			description:      "it should give up after the retries",
+			failures:         10,
+			status:           http.StatusBadGateway,
+			expectedAttempts: 3,
+		},
+		{
+			description:      "it should give up when the server asks for a long wait",
+			failures:         1,
+			status:           http.StatusTooManyRequests,
+			retryAfter:       "3600",
+			expectedAttempts: 1,
+		},
+		{
+			description:      "it should not retry other failures",
+			failures:         1,
+			status:           http.StatusInternalServerError,
+			expectedStatus:   http.StatusInternalServerError,
+			expectedAttempts: 1,
+		},
+	}
+
+	for _, item := range data {
+		var attempts int32
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if int(atomic.AddInt32(&attempts, 1)) <= item.failures {
+				if item.retryAfter != "" {
+					w.Header().Set("Retry-After", item.retryAfter)
+				}
+
+				w.WriteHeader(item.status)
+				return
+			}
+		}))
+
+		client := http.Client{
+			Transport: &retryTransport{
+				transport: http.DefaultTransport,
+				retries:   2,
+				wait:      time.Millisecond,
+				maxWait:   time.Second,
+			},
+		}
+
+		resp, err := client.Get(server.URL)
+		server.Close()
+
+		if item.expectedStatus == 0 {
+			var retryErr *retryError
+			if !errors.As(err, &retryErr) {
+				t.Errorf("%s: expected a retry error and got %v", item.description, err)
+			} else if retryErr.Attempts != int(item.expectedAttempts) || retryErr.StatusCode != item.status {
+				t.Errorf("%s: unexpected error %v", item.description, retryErr)
+			}
+
+		} else if err != nil {
+			t.Errorf("%s: unexpected error %v", item.description, err)
+
+		} else {
+			resp.Body.Close()
+
+			if resp.StatusCode != item.expectedStatus {
+				t.Errorf("%s: expected status %d and got %d", item.description, item.expectedStatus, resp.StatusCode)
+			}
+		}
+
+		if attempts != item.expectedAttempts {
+			t.Errorf("%s: expected %d attempts and got %d", item.description, item.expectedAttempts, attempts)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	data := []struct {
+		description string
+		value       string
+		expected    time.Duration
+		expectedOK  bool
+	}{
+		{
+			description: "it should parse seconds",
+			value:       "120",
+			expected:    2 * time.Minute,
+			expectedOK:  true,
+		},
+		{
+			description: "it should consider past dates as no wait",
+			value:       "Wed, 21 Oct 2015 07:28:00 GMT",
+			expectedOK:  true,
+		},
+		{
+			description: "it should ignore invalid values",
+			value:       "soon",
+		},
+		{
+			description: "it should ignore negative values",
+			value:       "-1",
+		},
+	}
+
+	for _, item := range data {
+		wait, ok := parseRetryAfter(item.value)
+		if wait != item.expected || ok != item.expectedOK {
+			t.Errorf("%s: expected %s (%t) and got %s (%t)", item.description, item.expected, item.expectedOK, wait, ok)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/registrobr/rdap-client/output"
+	"github.com/urfave/cli"
+)
+
+// searchParameter maps a command line option to the query parameter of a
+// search, as defined in RFC 9082, section 3.2
+type searchParameter struct {
+	flag      string
+	parameter string
+	usage     string
+}
+
+var searchCommand = cli.Command{
+	Name:  "search",
+	Usage: "search objects in the RDAP server given with --host",
+	Subcommands: []cli.Command{
+		newSearchCommand("domains", "search domains", []searchParameter{
+			{flag: "name", parameter: "name", usage: "domain name pattern, like “nic*.br”"},
+			{flag: "ns-name", parameter: "nsLdhName", usage: "name pattern of a nameserver of the domain"},
+			{flag: "ns-ip", parameter: "nsIp", usage: "IP address of a nameserver of the domain"},
+		}),
+		newSearchCommand("entities", "search entities", []searchParameter{
+			{flag: "fn", parameter: "fn", usage: "full name pattern of the entity"},
+			{flag: "handle", parameter: "handle", usage: "handle pattern of the entity"},
+		}),
+		newSearchCommand("nameservers", "search nameservers", []searchParameter{
+			{flag: "name", parameter: "name", usage: "nameserver name pattern"},
+			{flag: "ip", parameter: "ip", usage: "IP address of the nameserver"},
+		}),
+	},
+}
+
+func newSearchCommand(path, usage string, parameters []searchParameter) cli.Command {
+	var flags []cli.Flag
+	for _, p := range parameters {
+		flags = append(flags, cli.StringFlag{
+			Name:  p.flag,
+			Usage: p.usage,
+		})
+	}
+
+	return cli.Command{
+		Name:  path,
+		Usage: usage,
+		Flags: flags,
+		Action: func(ctx *cli.Context) {
+			queryString, err := parseExtraOptions(globalContext(ctx).StringSlice("extra"))
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+
+			var criteria []string
+			for _, p := range parameters {
+				if value := ctx.String(p.flag); value != "" {
+					queryString.Set(p.parameter, value)
+					criteria = append(criteria, value)
+				}
+			}
+
+			if len(criteria) != 1 {
+				cli.ShowSubcommandHelp(ctx)
+				os.Exit(1)
+			}
+
+			searchAction(globalContext(ctx), path, criteria[0], queryString)
+		},
+	}
+}
+
+func searchAction(ctx *cli.Context, path, criterion string, queryString url.Values) {
+	outputType, printOptions, err := newPrintOptions(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	hosts := ctx.StringSlice("host")
+	if len(hosts) == 0 {
+		fmt.Fprintln(os.Stderr, "searches are not supported by the bootstrap, please inform the RDAP server with --host")
+		os.Exit(1)
+	}
+
+	_, rdapHTTPClient, err := newHTTPClients(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	start := time.Now()
+	result := &output.Result{
+		Query:      criterion,
+		ObjectType: path,
+	}
+
+	result.Object, result.Err = search(rdapHTTPClient, hosts, path, queryString, result)
+	result.Elapsed = time.Since(start)
+
+	status := 0
+	if err := printResult(result, outputType, printOptions); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		status = 1
+	}
+
+	if result.Err != nil {
+		status = 1
+	}
+
+	os.Exit(status)
+}
+
+// search sends the search query to each RDAP server until one of them
+// answers. The server details are stored in the query result
+func search(httpClient *http.Client, uris []string, path string, queryString url.Values, result *output.Result) (*output.SearchResults, error) {
+	var urls []string
+	for _, uri := range uris {
+		urls = append(urls, queryURL(uri, path, queryString))
+	}
+
+	fetcher := failoverFetcher{httpClient: httpClient, result: result}
+
+	resp, err := fetcher.fetchURLs(urls, nil)
+	if resp != nil {
+		defer resp.Body.Close()
+
+		result.Server = resp.Request.URL.String()
+		result.Status = resp.StatusCode
+		recordCache(result, resp)
+		recordAccess(result, resp)
+	} else {
+		recordError(result, err)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var results output.SearchResults
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		return nil, err
+	}
+
+	return &results, nil
+}
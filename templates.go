@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strings"
+)
+
+// templateNames lists the object types whose default template can be
+// replaced. Each one is loaded from the file “<name>.tmpl” of the templates
+// directory
+var templateNames = []string{"domain", "autnum", "ip", "entity", "nameserver", "object"}
+
+// loadTemplates reads the user templates that replace the default ones. The
+// templates are searched in the templates directory, where a missing file
+// keeps the default template for that object type, and the template files
+// given in the command line, in the format “TYPE=FILE”, replace the template
+// of their object type
+func loadTemplates(dir string, files []string) (map[string]string, error) {
+	templates := make(map[string]string)
+
+	for _, name := range templateNames {
+		content, err := os.ReadFile(path.Join(dir, name+".tmpl"))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		templates[name] = string(content)
+	}
+
+	for _, value := range files {
+		name, file, ok := strings.Cut(value, "=")
+		if !ok || !slices.Contains(templateNames, name) {
+			return nil, fmt.Errorf("invalid template “%s”, expected “TYPE=FILE” with the type %s",
+				value, strings.Join(templateNames, ", "))
+		}
+
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		templates[name] = string(content)
+	}
+
+	return templates, nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadTemplates(t *testing.T) {
+	dir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(dir, "domain.tmpl"), []byte("dir domain"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "entity.tmpl"), []byte("dir entity"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	file := filepath.Join(t.TempDir(), "oneline.tmpl")
+	if err := os.WriteFile(file, []byte("oneline"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	templates, err := loadTemplates(dir, []string{"domain=" + file})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{"domain": "oneline", "entity": "dir entity"}
+	if !reflect.DeepEqual(templates, expected) {
+		t.Errorf("expected %v and got %v", expected, templates)
+	}
+
+	for _, value := range []string{file, "contact=" + file} {
+		if _, err := loadTemplates(dir, []string{value}); err == nil {
+			t.Errorf("expected an error for “%s”", value)
+		}
+	}
+}
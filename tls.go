@@ -0,0 +1,162 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/urfave/cli"
+)
+
+// pinRule pins the public key of the certificates of the hosts matching a
+// pattern, given by the base64 encoded SHA-256 hash of its SPKI
+type pinRule struct {
+	pattern string
+	hash    string
+}
+
+// parsePins reads the pins in the format “[pattern=]sha256//hash”, where a
+// missing pattern matches any host. The hash can be obtained with:
+//
+//	openssl x509 -in cert.pem -pubkey -noout | openssl pkey -pubin -outform der |
+//	  openssl dgst -sha256 -binary | base64
+func parsePins(pins []string) ([]pinRule, error) {
+	var rules []pinRule
+
+	for _, pin := range pins {
+		rule := pinRule{pattern: "*"}
+
+		// the hash may end with the “=” of the base64 padding
+		pos := strings.Index(pin, "sha256//")
+		if pos > 0 && pin[pos-1] == '=' {
+			rule.pattern = strings.ToLower(pin[:pos-1])
+		} else if pos != 0 {
+			return nil, fmt.Errorf("invalid pin “%s”, expected “[pattern=]sha256//hash”", pin)
+		}
+
+		if _, err := path.Match(rule.pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid host pattern in “%s”: %w", pin, err)
+		}
+
+		hash := pin[pos+len("sha256//"):]
+		if decoded, err := base64.StdEncoding.DecodeString(hash); err != nil || len(decoded) != sha256.Size {
+			return nil, fmt.Errorf("invalid pin “%s”, expected a base64 encoded SHA-256 hash", pin)
+		}
+
+		rule.hash = hash
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+func spkiHash(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// verifyPins checks that a certificate of the verified chains has one of the
+// public keys pinned by the first pattern matching the host. All the pins of
+// that pattern are accepted, so keys can be rotated. The other certificates
+// sent by the server are ignored, as anyone can send a public certificate,
+// and without verification only the server certificate is checked. The host
+// is the name sent with SNI, so the servers reached by IP address are only
+// checked by the pins without a pattern
+func verifyPins(rules []pinRule, skipVerify bool) func(tls.ConnectionState) error {
+	return func(cs tls.ConnectionState) error {
+		host := strings.ToLower(cs.ServerName)
+
+		var pattern string
+		for _, rule := range rules {
+			if ok, _ := path.Match(rule.pattern, host); ok {
+				pattern = rule.pattern
+				break
+			}
+		}
+
+		if pattern == "" {
+			return nil
+		}
+
+		var certs []*x509.Certificate
+		for _, chain := range cs.VerifiedChains {
+			certs = append(certs, chain...)
+		}
+
+		if skipVerify && len(cs.PeerCertificates) > 0 {
+			certs = cs.PeerCertificates[:1]
+		}
+
+		for _, cert := range certs {
+			hash := spkiHash(cert)
+
+			for _, rule := range rules {
+				if rule.pattern == pattern && rule.hash == hash {
+					return nil
+				}
+			}
+		}
+
+		return fmt.Errorf("the certificate of %s doesn't match the pinned public keys", host)
+	}
+}
+
+// newTLSConfig builds the TLS settings of the HTTP clients from the global
+// options. The CA file is added to the system roots, so the public servers
+// are still verified
+func newTLSConfig(ctx *cli.Context) (*tls.Config, error) {
+	var (
+		caFile     = ctx.String("ca-file")
+		clientCert = ctx.String("client-cert")
+		clientKey  = ctx.String("client-key")
+	)
+
+	config := &tls.Config{
+		InsecureSkipVerify: ctx.Bool("skip-tls-verification"),
+	}
+
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+
+		if config.RootCAs, err = x509.SystemCertPool(); err != nil {
+			config.RootCAs = x509.NewCertPool()
+		}
+
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", caFile)
+		}
+	}
+
+	if clientCert != "" || clientKey != "" {
+		if clientCert == "" || clientKey == "" {
+			return nil, errors.New("the client certificate needs both --client-cert and --client-key")
+		}
+
+		cert, err := tls.LoadX509KeyPair(clientCert, clientKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid client certificate: %w", err)
+		}
+
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	pins, err := parsePins(ctx.StringSlice("pin"))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(pins) > 0 {
+		config.VerifyConnection = verifyPins(pins, config.InsecureSkipVerify)
+	}
+
+	return config, nil
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"flag"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/urfave/cli"
+)
+
+func TestParsePins(t *testing.T) {
+	hash := "47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU="
+
+	rules, err := parsePins([]string{"sha256//" + hash, "*.registro.br=sha256//" + hash})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rules[0].pattern != "*" || rules[1].pattern != "*.registro.br" || rules[1].hash != hash {
+		t.Errorf("unexpected rules %v", rules)
+	}
+
+	for _, pin := range []string{hash, "rdap.registro.br=sha256//abc", "rdap.registro.br=sha1//" + hash, "[=sha256//" + hash} {
+		if _, err := parsePins([]string{pin}); err == nil {
+			t.Errorf("expected an error for “%s”", pin)
+		}
+	}
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) == 0 {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	defer server.Close()
+
+	// the server also sends a certificate out of its chain, that anyone
+	// could send
+	extra := newSelfSignedCertificate(t)
+	server.TLS.Certificates[0].Certificate = append(server.TLS.Certificates[0].Certificate, extra.Raw)
+
+	// the certificate of the server is also used as the client certificate
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+
+	key, err := x509.MarshalPKCS8PrivateKey(server.TLS.Certificates[0].PrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(certFile, certPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: key})
+	if err := os.WriteFile(keyFile, keyPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	pin := "sha256//" + spkiHash(server.Certificate())
+	otherPin := "sha256//47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU="
+	extraPin := "sha256//" + spkiHash(extra)
+
+	data := []struct {
+		description    string
+		arguments      []string
+		expectedStatus int
+		expectedError  bool
+	}{
+		{
+			description:   "it should not trust unknown CAs",
+			expectedError: true,
+		},
+		{
+			description:    "it should trust the CA file",
+			arguments:      []string{"--ca-file", certFile},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			description:    "it should send the client certificate",
+			arguments:      []string{"--ca-file", certFile, "--client-cert", certFile, "--client-key", keyFile},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			description:    "it should accept the pinned public key",
+			arguments:      []string{"--ca-file", certFile, "--pin", pin},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			description:   "it should reject other public keys",
+			arguments:     []string{"--skip-tls-verification", "--pin", otherPin},
+			expectedError: true,
+		},
+		{
+			description:   "it should reject the pinned public keys out of the verified chain",
+			arguments:     []string{"--ca-file", certFile, "--pin", extraPin},
+			expectedError: true,
+		},
+		{
+			description:   "it should only check the server certificate without verification",
+			arguments:     []string{"--skip-tls-verification", "--pin", extraPin},
+			expectedError: true,
+		},
+		{
+			description:    "it should accept the pinned server certificate without verification",
+			arguments:      []string{"--skip-tls-verification", "--pin", pin},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			description:    "it should not check the pins of other hosts",
+			arguments:      []string{"--skip-tls-verification", "--pin", "rdap.registro.br=" + otherPin},
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, item := range data {
+		set := flag.NewFlagSet("test", flag.ContinueOnError)
+		set.String("ca-file", "", "")
+		set.String("client-cert", "", "")
+		set.String("client-key", "", "")
+		set.Bool("skip-tls-verification", false, "")
+		set.Var(&cli.StringSlice{}, "pin", "")
+
+		if err := set.Parse(item.arguments); err != nil {
+			t.Fatal(err)
+		}
+
+		config, err := newTLSConfig(cli.NewContext(nil, set, nil))
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", item.description, err)
+		}
+
+		client := http.Client{Transport: &http.Transport{TLSClientConfig: config}}
+
+		resp, err := client.Get(server.URL)
+		if item.expectedError {
+			if err == nil {
+				resp.Body.Close()
+				t.Errorf("%s: expected an error", item.description)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", item.description, err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != item.expectedStatus {
+			t.Errorf("%s: expected status %d and got %d", item.description, item.expectedStatus, resp.StatusCode)
+		}
+	}
+}
+
+func newSelfSignedCertificate(t *testing.T) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cert
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/urfave/cli"
+)
+
+// tracedRequestHeaders and tracedResponseHeaders are the headers written to
+// the trace
+var (
+	tracedRequestHeaders  = []string{"Accept", "Cache-Control"}
+	tracedResponseHeaders = []string{"Content-Type", "Content-Length", "Cache-Control", "Expires", "Age", "ETag", "Last-Modified", "Location", "Retry-After"}
+)
+
+// tracer writes how the queries are resolved, like the bootstrap entries and
+// the HTTP requests. The lines of each request are numbered, as concurrent
+// queries are traced at the same time
+type tracer struct {
+	w io.Writer
+
+	mu       sync.Mutex
+	requests int
+}
+
+// traceOf returns the tracer of the application, or nil when the trace is
+// disabled
+func traceOf(ctx *cli.Context) *tracer {
+	t, _ := ctx.App.Metadata["tracer"].(*tracer)
+	return t
+}
+
+func (t *tracer) printf(format string, a ...any) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fmt.Fprintf(t.w, "* "+format+"\n", a...)
+}
+
+func (t *tracer) requestf(prefix string, id int, format string, a ...any) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fmt.Fprintf(t.w, "%s [%d] %s\n", prefix, id, fmt.Sprintf(format, a...))
+}
+
+func (t *tracer) next() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.requests++
+	return t.requests
+}
+
+type traceKey struct{}
+
+type requestTrace struct {
+	tracer *tracer
+	id     int
+}
+
+// tracef writes a line to the trace of the request, when it is traced, so
+// the decorators of the transports can tell what they did
+func tracef(req *http.Request, format string, a ...any) {
+	if rt, ok := req.Context().Value(traceKey{}).(*requestTrace); ok {
+		rt.tracer.requestf("*", rt.id, format, a...)
+	}
+}
+
+// traceTransport is a RoundTripper decorator that writes the requests and
+// the responses to the trace, with the details and the timing of each phase
+// of the connections. Placed above the cache, it also shows the cached
+// responses, and each redirect is traced as a new request
+type traceTransport struct {
+	transport http.RoundTripper
+	tracer    *tracer
+}
+
+func (t *traceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	id := t.tracer.next()
+	start := time.Now()
+
+	t.tracer.requestf(">", id, "%s %s", req.Method, req.URL)
+	for _, name := range tracedRequestHeaders {
+		if value := req.Header.Get(name); value != "" {
+			t.tracer.requestf(">", id, "%s: %s", name, value)
+		}
+	}
+
+	ctx := context.WithValue(req.Context(), traceKey{}, &requestTrace{tracer: t.tracer, id: id})
+	ctx = httptrace.WithClientTrace(ctx, t.clientTrace(id))
+
+	resp, err := t.transport.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		t.tracer.requestf("*", id, "failed after %s: %v", milliseconds(time.Since(start)), err)
+		return resp, err
+	}
+
+	source := ""
+	if fromCache(resp) {
+		source = fmt.Sprintf(", from cache retrieved at %s", fetchedAt(resp).UTC().Format(time.RFC3339))
+	}
+
+	t.tracer.requestf("<", id, "%s in %s%s", resp.Status, milliseconds(time.Since(start)), source)
+	for _, name := range tracedResponseHeaders {
+		if value := resp.Header.Get(name); value != "" {
+			t.tracer.requestf("<", id, "%s: %s", name, value)
+		}
+	}
+
+	return resp, nil
+}
+
+// clientTrace times the phases of the connections of a request. Each
+// attempt of the request is traced, and the connection attempts to the
+// addresses of a host may overlap
+func (t *traceTransport) clientTrace(id int) *httptrace.ClientTrace {
+	var (
+		mu                          sync.Mutex
+		dnsStart, tlsStart, wroteAt time.Time
+		connectStart                = make(map[string]time.Time)
+	)
+
+	since := func(start *time.Time) string {
+		mu.Lock()
+		defer mu.Unlock()
+		return milliseconds(time.Since(*start))
+	}
+
+	set := func(start *time.Time) {
+		mu.Lock()
+		defer mu.Unlock()
+		*start = time.Now()
+	}
+
+	return &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			if info.Reused {
+				t.tracer.requestf("*", id, "reusing the connection to %s", info.Conn.RemoteAddr())
+			}
+		},
+		DNSStart: func(httptrace.DNSStartInfo) {
+			set(&dnsStart)
+		},
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			if info.Err != nil {
+				t.tracer.requestf("*", id, "DNS lookup failed after %s: %v", since(&dnsStart), info.Err)
+				return
+			}
+
+			var addrs []string
+			for _, addr := range info.Addrs {
+				addrs = append(addrs, addr.String())
+			}
+
+			t.tracer.requestf("*", id, "DNS lookup in %s: %s", since(&dnsStart), strings.Join(addrs, ", "))
+		},
+		ConnectStart: func(network, addr string) {
+			mu.Lock()
+			defer mu.Unlock()
+			connectStart[addr] = time.Now()
+		},
+		ConnectDone: func(network, addr string, err error) {
+			mu.Lock()
+			elapsed := milliseconds(time.Since(connectStart[addr]))
+			mu.Unlock()
+
+			if err != nil {
+				t.tracer.requestf("*", id, "connection to %s failed after %s: %v", addr, elapsed, err)
+			} else {
+				t.tracer.requestf("*", id, "connected to %s in %s", addr, elapsed)
+			}
+		},
+		TLSHandshakeStart: func() {
+			set(&tlsStart)
+		},
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			if err != nil {
+				t.tracer.requestf("*", id, "TLS handshake failed after %s: %v", since(&tlsStart), err)
+				return
+			}
+
+			t.tracer.requestf("*", id, "%s handshake in %s", tls.VersionName(state.Version), since(&tlsStart))
+			if len(state.PeerCertificates) > 0 {
+				cert := state.PeerCertificates[0]
+				t.tracer.requestf("*", id, "certificate “%s” issued by “%s”, expires at %s",
+					cert.Subject, cert.Issuer, cert.NotAfter.UTC().Format(time.RFC3339))
+			}
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			set(&wroteAt)
+		},
+		GotFirstResponseByte: func() {
+			t.tracer.requestf("*", id, "first response byte %s after the request", since(&wroteAt))
+		},
+	}
+}
+
+func milliseconds(d time.Duration) string {
+	return fmt.Sprintf("%.1fms", float64(d)/float64(time.Millisecond))
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTraceTransport(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/domain/example.br", http.StatusMovedPermanently)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/rdap+json")
+		w.Header().Set("Cache-Control", "max-age=60")
+	}))
+	defer server.Close()
+
+	var trace strings.Builder
+	client := http.Client{
+		Transport: &traceTransport{
+			transport: server.Client().Transport,
+			tracer:    &tracer{w: &trace},
+		},
+	}
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/old", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Accept", "application/rdap+json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	expected := []string{
+		"> [1] GET " + server.URL + "/old\n",
+		"> [1] Accept: application/rdap+json\n",
+		"* [1] connected to " + server.Listener.Addr().String() + " in ",
+		"* [1] TLS 1.3 handshake in ",
+		"* [1] certificate “O=Acme Co” issued by “O=Acme Co”",
+		"< [1] 301 Moved Permanently in ",
+		"< [1] Location: /domain/example.br\n",
+		"> [2] GET " + server.URL + "/domain/example.br\n",
+		"* [2] reusing the connection to ",
+		"< [2] 200 OK in ",
+		"< [2] Cache-Control: max-age=60\n",
+	}
+
+	output := trace.String()
+	for _, line := range expected {
+		if !strings.Contains(output, line) {
+			t.Errorf("expected “%s” in the trace:\n%s", strings.TrimSpace(line), output)
+		}
+	}
+}